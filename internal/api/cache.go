@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const cacheExt = ".cache"
+
+// CacheConfig defines the on-disk response cache.
+type CacheConfig struct {
+	Dir string
+	// TTL returns how long responses for an API path stay fresh.
+	// A zero duration disables caching for that path.
+	TTL func(path string) time.Duration
+	// Logf receives cache hit/store diagnostics. It may be nil.
+	Logf func(format string, args ...any)
+}
+
+// CachedClient serves fresh responses from disk and stores new ones.
+type CachedClient struct {
+	next Clienter
+	dir  string
+	ttl  func(path string) time.Duration
+	logf func(format string, args ...any)
+	now  func() time.Time
+}
+
+// CacheStats summarizes the entries in a cache directory.
+type CacheStats struct {
+	Dir     string `json:"dir"`
+	Entries int    `json:"entries"`
+	Expired int    `json:"expired"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// NewCachedClient wraps next with a cache rooted at cfg.Dir.
+func NewCachedClient(next Clienter, cfg CacheConfig) (*CachedClient, error) {
+	if next == nil {
+		return nil, errors.New("client is required")
+	}
+	if strings.TrimSpace(cfg.Dir) == "" {
+		return nil, errors.New("cache dir is required")
+	}
+	ttl := cfg.TTL
+	if ttl == nil {
+		ttl = DefaultTTL
+	}
+	return &CachedClient{
+		next: next,
+		dir:  cfg.Dir,
+		ttl:  ttl,
+		logf: cfg.Logf,
+		now:  time.Now,
+	}, nil
+}
+
+// DefaultTTL returns the cache lifetime for an API path. Station data is
+// kept for a day, live boards and vehicle positions only for seconds.
+// Routing results (journeys, trips, reachability) are not cached.
+func DefaultTTL(path string) time.Duration {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch segments[0] {
+	case "locations":
+		return 24 * time.Hour
+	case "stops":
+		switch {
+		case len(segments) == 2 && segments[1] == "reachable-from":
+			return 0
+		case len(segments) == 2:
+			return 24 * time.Hour
+		case len(segments) == 3 && (segments[2] == "departures" || segments[2] == "arrivals"):
+			return 30 * time.Second
+		}
+	case "radar":
+		return 10 * time.Second
+	}
+	return 0
+}
+
+// URL returns the fully qualified URL from the wrapped client.
+func (c *CachedClient) URL(path string, params url.Values) (string, error) {
+	return c.next.URL(path, params)
+}
+
+// Get returns a cached response while it is fresh and otherwise delegates
+// to the wrapped client, storing successful responses. Journey refreshes
+// (/journeys/{token}) ask for fresh data and are never cached.
+func (c *CachedClient) Get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	ttl := c.ttl(path)
+	if ttl <= 0 || isJourneyRefresh(path) {
+		return c.next.Get(ctx, path, params)
+	}
+	urlStr, err := c.next.URL(path, params)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(c.dir, cacheKey(urlStr)+cacheExt)
+	if body, ok := c.load(file, ttl); ok {
+		c.log("cache hit %s", urlStr)
+		return body, nil
+	}
+	body, err := c.next.Get(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store(file, path, body); err != nil {
+		c.log("cache store failed: %v", err)
+	}
+	if err := c.prune(file); err != nil {
+		c.log("cache prune failed: %v", err)
+	}
+	return body, nil
+}
+
+func isJourneyRefresh(path string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	return len(segments) == 2 && segments[0] == "journeys"
+}
+
+func (c *CachedClient) load(file string, ttl time.Duration) ([]byte, bool) {
+	info, err := os.Stat(file)
+	if err != nil || c.now().Sub(info.ModTime()) >= ttl {
+		return nil, false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	_, body, ok := splitEntry(data)
+	return body, ok
+}
+
+// store writes the entry atomically as "<api path>\n<body>" so stats can
+// apply the per-path TTL without keeping an index.
+func (c *CachedClient) store(file, path string, body []byte) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := fmt.Fprintf(tmp, "%s\n", path); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// prune deletes expired entries other than keep. Times such as --when now
+// are part of the URL, so without it such entries would pile up and never
+// be read again.
+func (c *CachedClient) prune(keep string) error {
+	now := c.now()
+	return eachEntry(c.dir, func(file string, info fs.FileInfo) error {
+		if file == keep {
+			return nil
+		}
+		path, err := readEntryPath(file)
+		if err != nil || now.Sub(info.ModTime()) >= c.ttl(path) {
+			if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *CachedClient) log(format string, args ...any) {
+	if c.logf != nil {
+		c.logf(format, args...)
+	}
+}
+
+// ReadCacheStats counts entries in dir, judging expiry with ttl.
+// A missing directory yields empty stats.
+func ReadCacheStats(dir string, ttl func(path string) time.Duration) (CacheStats, error) {
+	if ttl == nil {
+		ttl = DefaultTTL
+	}
+	stats := CacheStats{Dir: dir}
+	now := time.Now()
+	err := eachEntry(dir, func(file string, info fs.FileInfo) error {
+		stats.Entries++
+		stats.Bytes += info.Size()
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		path, _, ok := splitEntry(data)
+		if !ok || now.Sub(info.ModTime()) >= ttl(path) {
+			stats.Expired++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// ClearCache removes all cache entries in dir and returns how many were deleted.
+func ClearCache(dir string) (int, error) {
+	removed := 0
+	err := eachEntry(dir, func(file string, _ fs.FileInfo) error {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+func eachEntry(dir string, fn func(file string, info fs.FileInfo) error) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != cacheExt {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed meanwhile, e.g. by a concurrent prune.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(filepath.Join(dir, entry.Name()), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readEntryPath reads only the API path line of a cache entry.
+func readEntryPath(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+func splitEntry(data []byte) (string, []byte, bool) {
+	path, body, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return "", nil, false
+	}
+	return string(path), body, true
+}
+
+func cacheKey(urlStr string) string {
+	sum := sha256.Sum256([]byte(urlStr))
+	return hex.EncodeToString(sum[:])
+}
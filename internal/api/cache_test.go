@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type countingClient struct {
+	calls int
+}
+
+func (c *countingClient) Get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	c.calls++
+	return []byte(`[{"id":"8011160"}]`), nil
+}
+
+func (c *countingClient) URL(path string, params url.Values) (string, error) {
+	return buildURL(&url.URL{Scheme: "http", Host: "example.test"}, path, params)
+}
+
+func TestCachedClientServesFreshEntries(t *testing.T) {
+	next := &countingClient{}
+	dir := t.TempDir()
+	client, err := NewCachedClient(next, CacheConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewCachedClient error: %v", err)
+	}
+
+	params := url.Values{}
+	params.Set("query", "berlin")
+	for i := 0; i < 2; i++ {
+		body, err := client.Get(context.Background(), "/locations", params)
+		if err != nil {
+			t.Fatalf("Get error: %v", err)
+		}
+		if string(body) != `[{"id":"8011160"}]` {
+			t.Fatalf("unexpected body: %q", body)
+		}
+	}
+	if next.calls != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", next.calls)
+	}
+
+	client.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	if _, err := client.Get(context.Background(), "/locations", params); err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if next.calls != 2 {
+		t.Fatalf("expected expired entry to be refetched, got %d calls", next.calls)
+	}
+
+	stats, err := ReadCacheStats(dir, nil)
+	if err != nil {
+		t.Fatalf("ReadCacheStats error: %v", err)
+	}
+	if stats.Entries != 1 || stats.Expired != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	removed, err := ClearCache(dir)
+	if err != nil || removed != 1 {
+		t.Fatalf("ClearCache = %d, %v", removed, err)
+	}
+}
+
+func TestCachedClientSkipsUncachedPaths(t *testing.T) {
+	next := &countingClient{}
+	client, err := NewCachedClient(next, CacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewCachedClient error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), "/journeys", nil); err != nil {
+			t.Fatalf("Get error: %v", err)
+		}
+	}
+	if next.calls != 2 {
+		t.Fatalf("expected 2 upstream calls, got %d", next.calls)
+	}
+}
+
+func TestCachedClientPrunesExpiredEntries(t *testing.T) {
+	next := &countingClient{}
+	dir := t.TempDir()
+	client, err := NewCachedClient(next, CacheConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewCachedClient error: %v", err)
+	}
+	for _, when := range []string{"2024-02-01T08:00:00+01:00", "2024-02-01T08:00:01+01:00"} {
+		params := url.Values{}
+		params.Set("when", when)
+		if _, err := client.Get(context.Background(), "/stops/8011160/departures", params); err != nil {
+			t.Fatalf("Get error: %v", err)
+		}
+	}
+	stats, err := ReadCacheStats(dir, nil)
+	if err != nil || stats.Entries != 2 {
+		t.Fatalf("expected 2 entries, got %+v, %v", stats, err)
+	}
+
+	client.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if _, err := client.Get(context.Background(), "/locations", nil); err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	stats, err = ReadCacheStats(dir, nil)
+	if err != nil || stats.Entries != 1 || stats.Expired != 0 {
+		t.Fatalf("expected only the new entry to be left, got %+v, %v", stats, err)
+	}
+}
+
+func TestCachedClientNeverCachesJourneyRefresh(t *testing.T) {
+	next := &countingClient{}
+	dir := t.TempDir()
+	client, err := NewCachedClient(next, CacheConfig{
+		Dir: dir,
+		TTL: func(string) time.Duration { return time.Hour },
+	})
+	if err != nil {
+		t.Fatalf("NewCachedClient error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), "/journeys/T%24A%3D1", nil); err != nil {
+			t.Fatalf("Get error: %v", err)
+		}
+	}
+	if next.calls != 2 {
+		t.Fatalf("expected 2 upstream calls, got %d", next.calls)
+	}
+	if _, err := client.Get(context.Background(), "/journeys", nil); err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "/journeys", nil); err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if next.calls != 3 {
+		t.Fatalf("expected journeys searches to follow the TTL, got %d calls", next.calls)
+	}
+}
+
+func TestDefaultTTL(t *testing.T) {
+	cases := map[string]time.Duration{
+		"/locations":                24 * time.Hour,
+		"/stops/8011160":            24 * time.Hour,
+		"/stops/reachable-from":     0,
+		"/stops/8011160/departures": 30 * time.Second,
+		"/stops/8011160/arrivals":   30 * time.Second,
+		"/radar":                    10 * time.Second,
+		"/journeys":                 0,
+		"/trips/1%7C2":              0,
+	}
+	for path, want := range cases {
+		if got := DefaultTTL(path); got != want {
+			t.Errorf("DefaultTTL(%q) = %s, want %s", path, got, want)
+		}
+	}
+}
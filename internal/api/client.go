@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,6 +24,21 @@ type Config struct {
 	BaseURL   string
 	Timeout   time.Duration
 	UserAgent string
+	Retry     RetryPolicy
+	// Logf receives diagnostics such as retry attempts. It may be nil.
+	Logf func(format string, args ...any)
+}
+
+// RetryPolicy controls how transient failures are retried.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// Retries is the number of additional attempts after the first one.
+	Retries int
+	// BaseDelay is the backoff before the first retry; it doubles per attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and the Retry-After wait. When the server
+	// asks for a longer wait, Get gives up with an error saying so.
+	MaxDelay time.Duration
 }
 
 // Client wraps a base URL and an HTTP client for GET requests.
@@ -29,6 +46,9 @@ type Client struct {
 	baseURL   *url.URL
 	http      *http.Client
 	userAgent string
+	retry     RetryPolicy
+	logf      func(format string, args ...any)
+	sleep     func(ctx context.Context, d time.Duration) error
 }
 
 // NewClient creates a new API client from config.
@@ -43,12 +63,24 @@ func NewClient(cfg Config) (*Client, error) {
 	if cfg.Timeout <= 0 {
 		cfg.Timeout = 10 * time.Second
 	}
+	if cfg.Retry.Retries < 0 {
+		return nil, errors.New("retries must not be negative")
+	}
+	if cfg.Retry.BaseDelay <= 0 {
+		cfg.Retry.BaseDelay = 500 * time.Millisecond
+	}
+	if cfg.Retry.MaxDelay <= 0 {
+		cfg.Retry.MaxDelay = 10 * time.Second
+	}
 	return &Client{
 		baseURL: base,
 		http: &http.Client{
 			Timeout: cfg.Timeout,
 		},
 		userAgent: cfg.UserAgent,
+		retry:     cfg.Retry,
+		logf:      cfg.Logf,
+		sleep:     SleepContext,
 	}, nil
 }
 
@@ -58,6 +90,7 @@ func (c *Client) URL(path string, params url.Values) (string, error) {
 }
 
 // Get issues a GET request against the API and returns the response body.
+// Network errors and transient HTTP statuses are retried per the RetryPolicy.
 func (c *Client) Get(ctx context.Context, path string, params url.Values) ([]byte, error) {
 	if c == nil || c.baseURL == nil {
 		return nil, errors.New("client is not configured")
@@ -66,9 +99,35 @@ func (c *Client) Get(ctx context.Context, path string, params url.Values) ([]byt
 	if err != nil {
 		return nil, err
 	}
+	attempts := c.retry.Retries + 1
+	for attempt := 1; ; attempt++ {
+		body, retryAfter, err := c.do(ctx, urlStr)
+		if err == nil {
+			return body, nil
+		}
+		if attempt >= attempts || !retryable(ctx, err) {
+			return nil, err
+		}
+		wait := c.backoff(attempt)
+		if retryAfter > 0 {
+			if retryAfter > c.retry.MaxDelay {
+				return nil, fmt.Errorf("%w (server asked to retry after %s, more than the %s limit; giving up)", err, retryAfter, c.retry.MaxDelay)
+			}
+			wait = retryAfter
+		}
+		c.log("attempt %d/%d failed: %v; retrying in %s", attempt, attempts, err, wait.Round(time.Millisecond))
+		if errSleep := c.sleep(ctx, wait); errSleep != nil {
+			return nil, errSleep
+		}
+	}
+}
+
+// do performs a single GET attempt. On failure it also returns the delay
+// requested by a Retry-After header, if any.
+func (c *Client) do(ctx context.Context, urlStr string) ([]byte, time.Duration, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, 0, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	if c.userAgent != "" {
@@ -76,19 +135,105 @@ func (c *Client) Get(ctx context.Context, path string, params url.Values) ([]byt
 	}
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, 0, networkError{fmt.Errorf("request failed: %w", err)}
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+		return nil, 0, networkError{fmt.Errorf("read response: %w", err)}
 	}
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return nil, HTTPError{Status: resp.StatusCode, Body: body}
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), HTTPError{Status: resp.StatusCode, Body: body}
+	}
+	return body, 0, nil
+}
+
+// backoff returns the exponential delay before the given retry, with jitter
+// in the upper half of the interval so concurrent clients spread out.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.BaseDelay
+	for i := 1; i < attempt && delay < c.retry.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (c *Client) log(format string, args ...any) {
+	if c.logf != nil {
+		c.logf(format, args...)
+	}
+}
+
+// networkError marks a failure to send a request or read its response,
+// as opposed to an error building the request.
+type networkError struct {
+	error
+}
+
+func (e networkError) Unwrap() error {
+	return e.error
+}
+
+// retryable reports whether a failed attempt may succeed when repeated.
+// Only GET requests are issued, so every transient failure is safe to retry.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.Status {
+		case http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var netErr networkError
+	return errors.As(err, &netErr)
+}
+
+// parseRetryAfter accepts both forms of the header: delta seconds and an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// SleepContext waits for d or until ctx is done, returning ctx's error in
+// the latter case.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	return body, nil
 }
 
 func buildURL(base *url.URL, path string, params url.Values) (string, error) {
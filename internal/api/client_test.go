@@ -2,10 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestClientGetBuildsURL(t *testing.T) {
@@ -66,3 +70,165 @@ func TestClientGetError(t *testing.T) {
 		t.Fatalf("expected HTTPError, got %T", err)
 	}
 }
+
+func TestClientGetRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var logged []string
+	client, err := NewClient(Config{
+		BaseURL: server.URL,
+		Retry:   RetryPolicy{Retries: 2, BaseDelay: time.Millisecond},
+		Logf: func(format string, args ...any) {
+			logged = append(logged, fmt.Sprintf(format, args...))
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	body, err := client.Get(context.Background(), "/locations", nil)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if string(body) != "[]" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if len(logged) != 2 {
+		t.Fatalf("expected 2 retry log lines, got %q", logged)
+	}
+}
+
+func TestClientGetDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		BaseURL: server.URL,
+		Retry:   RetryPolicy{Retries: 3, BaseDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	if _, err := client.Get(context.Background(), "/locations", nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestClientGetReportsCancelDuringBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		BaseURL: server.URL,
+		Retry:   RetryPolicy{Retries: 2, BaseDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	client.sleep = func(context.Context, time.Duration) error {
+		return context.Canceled
+	}
+
+	if _, err := client.Get(context.Background(), "/locations", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	ctx := context.Background()
+	if !retryable(ctx, networkError{errors.New("request failed: connection refused")}) {
+		t.Fatal("expected network errors to be retried")
+	}
+	if retryable(ctx, fmt.Errorf("create request: %w", errors.New("invalid URL"))) {
+		t.Fatal("expected request construction errors not to be retried")
+	}
+	if retryable(ctx, HTTPError{Status: http.StatusNotFound}) {
+		t.Fatal("expected 404 not to be retried")
+	}
+}
+
+func TestClientGetHonoursRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		BaseURL: server.URL,
+		Retry:   RetryPolicy{Retries: 1, BaseDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	var slept time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = d
+		return nil
+	}
+
+	if _, err := client.Get(context.Background(), "/locations", nil); err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if slept != 7*time.Second {
+		t.Fatalf("expected 7s wait from Retry-After, got %s", slept)
+	}
+}
+
+func TestClientGetGivesUpOnLongRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		BaseURL: server.URL,
+		Retry:   RetryPolicy{Retries: 2, BaseDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	_, err = client.Get(context.Background(), "/locations", nil)
+	var httpErr HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusTooManyRequests {
+		t.Fatalf("expected the 429 HTTPError, got %v", err)
+	}
+	if want := "server asked to retry after 2m0s, more than the 10s limit; giving up"; !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected %q in %q", want, err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 attempt, got %d", calls)
+	}
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/config"
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+// expandAlias replaces an "@name" value with the stops of the saved alias,
+// several for a group. Other values are returned as the only stop.
+func expandAlias(sess *session, value string) ([]string, error) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(value), "@")
+	if !ok {
+		return []string{value}, nil
+	}
+	saved, ok := sess.conf.Aliases[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown alias @%s (see 'dbrest alias list')", name)
+	}
+	return saved, nil
+}
+
+// expandAliases expands every pointed-to value in place and reports the
+// first unknown alias, or group alias, to stderr.
+func expandAliases(sess *session, values ...*string) bool {
+	for _, value := range values {
+		expanded, err := expandAlias(sess, *value)
+		if err == nil && len(expanded) > 1 {
+			err = fmt.Errorf("%s is a group of %d stops; only departures takes a group", strings.TrimSpace(*value), len(expanded))
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return false
+		}
+		*value = expanded[0]
+	}
+	return true
+}
+
+// aliasStops shows the stops of an alias on one line.
+func aliasStops(stops []string) string {
+	return strings.Join(stops, " + ")
+}
+
+func runAlias(args []string, sess *session) int {
+	if len(args) == 0 {
+		printAliasUsage(sess.errOut)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "--help" {
+		printAliasUsage(sess.out)
+		return exitOK
+	}
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			_, _ = fmt.Fprintln(sess.errOut, "usage: dbrest alias add <name> <stop>...")
+			return exitUsage
+		}
+		name := strings.TrimPrefix(args[1], "@")
+		if !config.ValidAliasName(name) {
+			_, _ = fmt.Fprintf(sess.errOut, "invalid alias name %q (use letters, digits, - and _)\n", args[1])
+			return exitUsage
+		}
+		if err := config.SetAlias(sess.conf.Path, name, args[2:]...); err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return exitError
+		}
+		if sess.mode == OutputHuman {
+			_, _ = fmt.Fprintf(sess.out, "@%s = %s\n", name, aliasStops(args[2:]))
+		}
+		return exitOK
+	case "rm":
+		if len(args) != 2 {
+			_, _ = fmt.Fprintln(sess.errOut, "usage: dbrest alias rm <name>")
+			return exitUsage
+		}
+		name := strings.TrimPrefix(args[1], "@")
+		if _, ok := sess.conf.Aliases[name]; !ok {
+			_, _ = fmt.Fprintf(sess.errOut, "unknown alias @%s\n", name)
+			return exitError
+		}
+		if err := config.RemoveAlias(sess.conf.Path, name); err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return exitError
+		}
+		return exitOK
+	case "list":
+		if len(args) != 1 {
+			_, _ = fmt.Fprintln(sess.errOut, "usage: dbrest alias list")
+			return exitUsage
+		}
+		return listAliases(sess)
+	default:
+		_, _ = fmt.Fprintf(sess.errOut, "unknown alias command: %s\n", args[0])
+		printAliasUsage(sess.errOut)
+		return exitUsage
+	}
+}
+
+func listAliases(sess *session) int {
+	if sess.mode == OutputJSON {
+		aliases := make(map[string]any, len(sess.conf.Aliases))
+		for name, stops := range sess.conf.Aliases {
+			aliases[name] = stops
+			if len(stops) == 1 {
+				aliases[name] = stops[0]
+			}
+		}
+		data, err := json.Marshal(aliases)
+		if err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return exitError
+		}
+		writeJSON(sess.out, data)
+		return exitOK
+	}
+	t := format.Table{Columns: []format.Column{{Name: "name"}, {Name: "stop"}}}
+	for _, name := range sess.conf.AliasNames() {
+		t.Rows = append(t.Rows, format.Row{Cells: []string{name, aliasStops(sess.conf.Aliases[name])}})
+	}
+	return writeTable(sess, t)
+}
+
+func printAliasUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest alias add <name> <stop>...
+  dbrest alias list
+  dbrest alias rm <name>
+
+COMMANDS:
+  add            Save stop ids (or names) under <name>, replacing any existing alias
+  list           Print all aliases
+  rm             Remove an alias
+
+NOTE:
+  Aliases are stored in the [aliases] table of the config file. Write
+  @<name> for --stop, --from, --to, --via or a trip id to use one.
+  Names may contain letters, digits, - and _. An alias of several stops
+  is a group: departures merges their boards, other commands reject it.
+  --plain prints: name, stop (a group's stops joined by " + "). --json
+  prints an object of name to stop, or to an array for a group.
+
+EXAMPLE:
+  dbrest alias add home 8011160
+  dbrest departures @home
+  dbrest alias add office 900100003 900003201
+  dbrest departures @office
+  dbrest alias add work "Hamburg Hbf"
+  dbrest journeys --from @home --to @work`)
+}
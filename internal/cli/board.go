@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+// stopList collects a repeatable --stop flag.
+type stopList []string
+
+func (s *stopList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stopList) Set(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("stop must not be empty")
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// expandStops expands @aliases, where a group alias stands for all of its
+// stops, and reports unknown ones to stderr.
+func expandStops(sess *session, stops []string) ([]string, bool) {
+	var expanded []string
+	for _, stop := range stops {
+		values, err := expandAlias(sess, stop)
+		if err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return nil, false
+		}
+		expanded = append(expanded, values...)
+	}
+	return expanded, true
+}
+
+// mergeDepartures fetches the departures of every stop concurrently and
+// merges them into one board of at most results departures (all for 0).
+// A stop that cannot be resolved or fetched is reported to stderr and left
+// out, and complete is false; data is nil when no stop succeeded.
+func mergeDepartures(sess *session, stops []string, values url.Values, results int, strict bool) (data []byte, complete bool) {
+	var ids []string
+	complete = true
+	for _, stop := range stops {
+		id, err := resolveStop(sess, stop, strict)
+		if err != nil {
+			complete = false
+			continue
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, false
+	}
+
+	paths := make([]string, len(ids))
+	for i, id := range ids {
+		paths[i] = "/stops/" + url.PathEscape(id) + "/departures"
+		if sess.verbose {
+			if urlStr, err := sess.client.URL(paths[i], values); err == nil {
+				_, _ = fmt.Fprintf(sess.errOut, "GET %s\n", urlStr)
+			}
+		}
+	}
+	bodies := make([][]byte, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bodies[i], errs[i] = sess.client.Get(sess.ctx, path, values)
+		}()
+	}
+	wg.Wait()
+
+	var fetched [][]byte
+	for i, err := range errs {
+		if err != nil {
+			complete = false
+			if sess.ctx.Err() == nil {
+				_, _ = fmt.Fprintf(sess.errOut, "stop %s: %v\n", ids[i], err)
+			}
+			continue
+		}
+		fetched = append(fetched, bodies[i])
+	}
+	if len(fetched) == 0 {
+		return nil, false
+	}
+	data, err := format.MergeStopovers(fetched, results)
+	if err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
+		return nil, false
+	}
+	return data, complete
+}
+
+// followTrip prints the rest of the trip of the nth (from 1) stopover of
+// a departures board, from the board's stop on.
+func followTrip(sess *session, board []byte, n int) int {
+	table, err := format.StopoversTable(board)
+	if err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
+		return exitError
+	}
+	if n > len(table.Rows) {
+		_, _ = fmt.Fprintf(sess.errOut, "--follow %d: the board has only %d departures\n", n, len(table.Rows))
+		return exitError
+	}
+	stopover, _ := table.Rows[n-1].Record.(format.Stopover)
+	if stopover.TripID == "" {
+		_, _ = fmt.Fprintf(sess.errOut, "--follow %d: departure has no trip id\n", n)
+		return exitError
+	}
+	_, _ = fmt.Fprintf(sess.errOut, "following %s to %s (%s)\n", stopover.Line.Name, stopover.Direction, stopover.TripID)
+	data, err := fetch(sess, "/trips/"+url.PathEscape(stopover.TripID), url.Values{})
+	if err != nil {
+		return exitError
+	}
+	return render(sess, data, func(data []byte) (format.Table, error) {
+		return format.RemainingTripTable(data, stopover.Stop.ID)
+	})
+}
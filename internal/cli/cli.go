@@ -2,17 +2,22 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/timkrase/deutsche-bahn-skill/internal/api"
+	"github.com/timkrase/deutsche-bahn-skill/internal/config"
 	"github.com/timkrase/deutsche-bahn-skill/internal/format"
 )
 
@@ -28,8 +33,34 @@ const (
 	OutputHuman OutputMode = iota
 	OutputPlain
 	OutputJSON
+	OutputCSV
+	OutputTSV
+	OutputNDJSON
+	OutputGeoJSON
+	OutputICS
+	// OutputTemplate is selected by --template; it has no --output name.
+	OutputTemplate
 )
 
+// outputModeNames are the --output values, indexed by OutputMode.
+var outputModeNames = []string{"human", "plain", "json", "csv", "tsv", "ndjson", "geojson", "ics"}
+
+// modeCommands lists the only commands an output mode works for; help,
+// completion and request accept every mode.
+var modeCommands = map[OutputMode][]string{
+	OutputGeoJSON: {"locations", "nearby", "reachable", "stop", "radar", "trip"},
+	OutputICS:     {"journeys", "journey"},
+}
+
+func parseOutputMode(name string) (OutputMode, bool) {
+	for i, candidate := range outputModeNames {
+		if candidate == name {
+			return OutputMode(i), true
+		}
+	}
+	return 0, false
+}
+
 // Runner wires dependencies for CLI execution.
 type Runner struct {
 	Out       io.Writer
@@ -39,6 +70,31 @@ type Runner struct {
 	Version   string
 }
 
+// session carries the resolved global flags and dependencies shared by
+// all subcommands of one invocation.
+type session struct {
+	ctx      context.Context
+	out      io.Writer
+	errOut   io.Writer
+	client   api.Clienter
+	live     api.Clienter // bypasses the response cache, for polling
+	mode     OutputMode
+	fields   []string // --fields selection, empty for the default columns
+	header   bool     // csv/tsv output starts with a header row
+	template *template.Template
+	verbose  bool
+	tz       *time.Location // --tz, for resolving relative times
+	times    format.TimeOptions
+	terminal bool // out is a TTY
+	table    format.TableOptions
+	profile  config.Profile
+	conf     config.File
+	settings []setting
+	// collect, if set, receives each command's flag set instead of
+	// running the command; see describe.
+	collect func(fs *flag.FlagSet)
+}
+
 // Run executes the CLI with the provided args and returns an exit code.
 func Run(args []string, runner Runner) int {
 	out := runner.Out
@@ -64,13 +120,24 @@ func Run(args []string, runner Runner) int {
 	fs.SetOutput(io.Discard)
 
 	var (
-		helpFlag   bool
-		version    bool
-		jsonOutput bool
-		plain      bool
-		baseURL    string
-		timeoutStr string
-		verbose    bool
+		helpFlag    bool
+		version     bool
+		jsonOutput  bool
+		plain       bool
+		output      string
+		noHeader    bool
+		fields      string
+		tmplText    string
+		tmplFile    string
+		baseURL     string
+		timeoutStr  string
+		retriesStr  string
+		noCache     bool
+		cacheTTL    string
+		profileName string
+		tzName      string
+		timeFormat  string
+		verbose     bool
 	)
 
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
@@ -78,9 +145,20 @@ func Run(args []string, runner Runner) int {
 	fs.BoolVar(&version, "version", false, "Show version")
 	fs.BoolVar(&jsonOutput, "json", false, "Output raw JSON")
 	fs.BoolVar(&plain, "plain", false, "Output stable, line-based text")
-	fs.BoolVar(&verbose, "verbose", false, "Print request details to stderr")
-	fs.StringVar(&baseURL, "base-url", envOrDefault(getenv, "DBREST_BASE_URL", "https://v6.db.transport.rest"), "API base URL")
-	fs.StringVar(&timeoutStr, "timeout", envOrDefault(getenv, "DBREST_TIMEOUT", "10s"), "HTTP timeout (e.g. 10s, 1m)")
+	fs.StringVar(&output, "output", "", "Output mode: human, plain, json, csv, tsv, ndjson, geojson or ics")
+	fs.BoolVar(&noHeader, "no-header", false, "Omit the csv/tsv header row")
+	fs.StringVar(&fields, "fields", "", "Comma-separated columns to print, in order")
+	fs.StringVar(&tmplText, "template", "", "Go template rendered once per record")
+	fs.StringVar(&tmplFile, "template-file", "", "Read the --template from a file")
+	fs.BoolVar(&verbose, "verbose", false, "Print request details and retries to stderr")
+	fs.StringVar(&baseURL, "base-url", "", "API base URL")
+	fs.StringVar(&timeoutStr, "timeout", "", "HTTP timeout (e.g. 10s, 1m)")
+	fs.StringVar(&retriesStr, "retries", "", "Retries for transient failures")
+	fs.BoolVar(&noCache, "no-cache", false, "Bypass the response cache")
+	fs.StringVar(&cacheTTL, "cache-ttl", "", "Cache lifetime for all endpoints (e.g. 5m)")
+	fs.StringVar(&profileName, "profile", "", "Config profile to use")
+	fs.StringVar(&tzName, "tz", defaultTimeZone, "Time zone for relative times and time columns")
+	fs.StringVar(&timeFormat, "time-format", "", "Time columns: iso, hh:mm, relative or a Go layout")
 
 	fs.Usage = func() {
 		printUsage(errOut)
@@ -106,56 +184,246 @@ func Run(args []string, runner Runner) int {
 		_, _ = fmt.Fprintln(errOut, "--json and --plain are mutually exclusive")
 		return exitUsage
 	}
+	if output != "" && (jsonOutput || plain) {
+		_, _ = fmt.Fprintln(errOut, "--output cannot be combined with --json or --plain")
+		return exitUsage
+	}
+	if tmplText != "" && tmplFile != "" {
+		_, _ = fmt.Fprintln(errOut, "--template and --template-file are mutually exclusive")
+		return exitUsage
+	}
+	if tmplFile != "" {
+		data, err := os.ReadFile(tmplFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(errOut, "invalid --template-file: %v\n", err)
+			return exitUsage
+		}
+		tmplText = strings.TrimSuffix(string(data), "\n")
+	}
+	if tmplText != "" && (output != "" || jsonOutput || plain) {
+		_, _ = fmt.Fprintln(errOut, "--template cannot be combined with --output, --json or --plain")
+		return exitUsage
+	}
 
-	mode := OutputHuman
+	// help, completion and config still work with a broken config file, so
+	// it can be inspected and fixed; they run without profiles.
+	conf, confErr := config.Load(config.DefaultPath(getenv))
+	if confErr != nil {
+		switch fs.Arg(0) {
+		case "help", "completion", "__complete", "config":
+		default:
+			_, _ = fmt.Fprintf(errOut, "invalid config: %v\n", confErr)
+			return exitUsage
+		}
+		if fs.Arg(0) != "__complete" {
+			_, _ = fmt.Fprintf(errOut, "invalid config (ignored): %v\n", confErr)
+		}
+	}
+	flagSet := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		flagSet[f.Name] = true
+	})
+	res, err := newResolver(conf, profileName, getenv)
+	switch {
+	case err != nil && confErr != nil:
+		res = &resolver{getenv: getenv}
+	case err != nil:
+		_, _ = fmt.Fprintln(errOut, err)
+		return exitUsage
+	}
+
+	modeFlag := ""
 	if plain {
-		mode = OutputPlain
+		modeFlag = "plain"
 	}
 	if jsonOutput {
-		mode = OutputJSON
+		modeFlag = "json"
+	}
+	if output != "" {
+		modeFlag = output
+	}
+	if tmplText != "" {
+		modeFlag = "template"
+	}
+	settings := []setting{
+		{Key: "config", Value: conf.Path, Source: "default"},
+		res.profileSetting(),
+		res.resolve("base_url", baseURL, flagSet["base-url"], "DBREST_BASE_URL", res.profile.BaseURL, "https://v6.db.transport.rest"),
+		res.resolve("timeout", timeoutStr, flagSet["timeout"], "DBREST_TIMEOUT", res.profile.Timeout, "10s"),
+		res.resolve("retries", retriesStr, flagSet["retries"], "DBREST_RETRIES", "", "2"),
+		res.resolve("output", modeFlag, modeFlag != "", "", res.profile.Output, "human"),
+		res.resolve("results", "", false, "", formatOptionalInt(res.profile.Results), "-"),
+		res.resolve("products", "", false, "", strings.Join(res.profile.Products, ","), "-"),
+	}
+	resolved := map[string]string{}
+	for _, st := range settings {
+		resolved[st.Key] = st.Value
+	}
+
+	mode, ok := parseOutputMode(resolved["output"])
+	if tmplText != "" {
+		mode, ok = OutputTemplate, true
+	}
+	if !ok {
+		_, _ = fmt.Fprintf(errOut, "invalid output %q (expected %s)\n", resolved["output"], strings.Join(outputModeNames, ", "))
+		return exitUsage
 	}
 
-	timeout, err := time.ParseDuration(timeoutStr)
+	timeout, err := time.ParseDuration(resolved["timeout"])
 	if err != nil {
 		_, _ = fmt.Fprintf(errOut, "invalid --timeout: %v\n", err)
 		return exitUsage
 	}
 
+	retries, err := strconv.Atoi(resolved["retries"])
+	if err != nil || retries < 0 {
+		_, _ = fmt.Fprintf(errOut, "invalid --retries: %q (expected a non-negative integer)\n", resolved["retries"])
+		return exitUsage
+	}
+
+	products, err := parseProducts(res.profile.Products)
+	if err != nil {
+		_, _ = fmt.Fprintf(errOut, "invalid profile %s: %v\n", res.name, err)
+		return exitUsage
+	}
+	res.profile.Products = products
+
+	ttl := api.DefaultTTL
+	if cacheTTL != "" {
+		fixed, err := time.ParseDuration(cacheTTL)
+		if err != nil || fixed < 0 {
+			_, _ = fmt.Fprintf(errOut, "invalid --cache-ttl: %q (expected a duration like 5m)\n", cacheTTL)
+			return exitUsage
+		}
+		ttl = func(string) time.Duration { return fixed }
+	}
+
+	tz, err := time.LoadLocation(tzName)
+	if err != nil || tzName == "" {
+		_, _ = fmt.Fprintf(errOut, "invalid --tz: %q (expected an IANA zone like Europe/Berlin)\n", tzName)
+		return exitUsage
+	}
+
+	times, ok := parseTimeFormat(timeFormat, mode)
+	if !ok {
+		_, _ = fmt.Fprintf(errOut, "invalid --time-format: %q (expected iso, hh:mm, relative or a Go layout like 15:04)\n", timeFormat)
+		return exitUsage
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "tz" {
+			times.Location = tz
+		}
+	})
+
+	var tmpl *template.Template
+	if tmplText != "" {
+		parsed, err := format.ParseTemplate(tmplText, times)
+		if err != nil {
+			_, _ = fmt.Fprintf(errOut, "invalid --template: %v\n", err)
+			return exitUsage
+		}
+		tmpl = parsed
+	}
+
 	if fs.NArg() == 0 {
 		printUsage(errOut)
 		return exitUsage
 	}
 
-	client, err := newClient(api.Config{
-		BaseURL:   baseURL,
+	cfg := api.Config{
+		BaseURL:   resolved["base_url"],
 		Timeout:   timeout,
 		UserAgent: "dbrest/" + strings.TrimSpace(runner.Version),
-	})
+		Retry:     api.RetryPolicy{Retries: retries},
+	}
+	if verbose {
+		cfg.Logf = func(format string, args ...any) {
+			_, _ = fmt.Fprintf(errOut, format+"\n", args...)
+		}
+	}
+	client, err := newClient(cfg)
 	if err != nil {
 		_, _ = fmt.Fprintln(errOut, err)
 		return exitError
 	}
+	live := client
+	dir := cacheDir(getenv)
+	if !noCache && dir != "" {
+		cached, err := api.NewCachedClient(client, api.CacheConfig{Dir: dir, TTL: ttl, Logf: cfg.Logf})
+		if err != nil {
+			_, _ = fmt.Fprintln(errOut, err)
+			return exitError
+		}
+		client = cached
+	}
+
+	sess := &session{
+		ctx:      context.Background(),
+		out:      out,
+		errOut:   errOut,
+		client:   client,
+		live:     live,
+		mode:     mode,
+		fields:   splitList(fields),
+		header:   !noHeader,
+		template: tmpl,
+		verbose:  verbose,
+		tz:       tz,
+		times:    times,
+		terminal: isTerminalWriter(out),
+		table:    tableOptions(out, getenv),
+		profile:  res.profile,
+		conf:     conf,
+		settings: settings,
+	}
 
 	cmd := fs.Arg(0)
 	cmdArgs := fs.Args()[1:]
 
+	if commands, ok := modeCommands[mode]; ok && !slices.Contains(commands, cmd) {
+		switch cmd {
+		case "help", "completion", "__complete", "request":
+		default:
+			_, _ = fmt.Fprintf(errOut, "--output %s is only supported by %s\n", outputModeNames[mode], strings.Join(commands, ", "))
+			return exitUsage
+		}
+	}
+
 	switch cmd {
 	case "help":
 		return runHelp(cmdArgs, out, errOut)
+	case "cache":
+		return runCache(cmdArgs, sess, dir, ttl)
+	case "config":
+		return runConfig(cmdArgs, sess)
+	case "alias":
+		return runAlias(cmdArgs, sess)
+	case "completion":
+		return runCompletion(cmdArgs, sess, fs)
+	case "__complete":
+		return runComplete(cmdArgs, sess)
 	case "locations":
-		return runLocations(cmdArgs, out, errOut, client, mode, verbose)
+		return runLocations(cmdArgs, sess)
+	case "nearby":
+		return runNearby(cmdArgs, sess)
+	case "reachable":
+		return runReachable(cmdArgs, sess)
+	case "stop":
+		return runStop(cmdArgs, sess)
 	case "departures":
-		return runDepartures(cmdArgs, out, errOut, client, mode, verbose)
+		return runDepartures(cmdArgs, sess)
 	case "arrivals":
-		return runArrivals(cmdArgs, out, errOut, client, mode, verbose)
+		return runArrivals(cmdArgs, sess)
 	case "journeys":
-		return runJourneys(cmdArgs, out, errOut, client, mode, verbose)
+		return runJourneys(cmdArgs, sess)
+	case "journey":
+		return runJourney(cmdArgs, sess)
 	case "trip":
-		return runTrip(cmdArgs, out, errOut, client, mode, verbose)
+		return runTrip(cmdArgs, sess)
 	case "radar":
-		return runRadar(cmdArgs, out, errOut, client, mode, verbose)
+		return runRadar(cmdArgs, sess)
 	case "request":
-		return runRequest(cmdArgs, out, errOut, client, mode, verbose)
+		return runRequest(cmdArgs, sess)
 	default:
 		_, _ = fmt.Fprintf(errOut, "unknown command: %s\n", cmd)
 		printUsage(errOut)
@@ -169,20 +437,38 @@ func runHelp(args []string, out io.Writer, errOut io.Writer) int {
 		return exitOK
 	}
 	switch args[0] {
+	case "fields":
+		return runHelpFields(args[1:], out, errOut)
 	case "locations":
 		printLocationsUsage(out)
+	case "nearby":
+		printNearbyUsage(out)
+	case "reachable":
+		printReachableUsage(out)
+	case "stop":
+		printStopUsage(out)
 	case "departures":
 		printDeparturesUsage(out)
 	case "arrivals":
 		printArrivalsUsage(out)
 	case "journeys":
 		printJourneysUsage(out)
+	case "journey":
+		printJourneyUsage(out)
 	case "trip":
 		printTripUsage(out)
 	case "radar":
 		printRadarUsage(out)
 	case "request":
 		printRequestUsage(out)
+	case "cache":
+		printCacheUsage(out)
+	case "config":
+		printConfigUsage(out)
+	case "alias":
+		printAliasUsage(out)
+	case "completion":
+		printCompletionUsage(out)
 	default:
 		_, _ = fmt.Fprintf(errOut, "unknown command: %s\n", args[0])
 		printUsage(errOut)
@@ -235,7 +521,7 @@ func addParams(values url.Values, params []string) error {
 	return nil
 }
 
-func runLocations(args []string, out io.Writer, errOut io.Writer, client api.Clienter, mode OutputMode, verbose bool) int {
+func runLocations(args []string, sess *session) int {
 	fs := flag.NewFlagSet("locations", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
@@ -251,7 +537,7 @@ func runLocations(args []string, out io.Writer, errOut io.Writer, client api.Cli
 	)
 
 	fs.StringVar(&query, "query", "", "Search query")
-	fs.IntVar(&results, "results", 10, "Maximum number of results")
+	fs.IntVar(&results, "results", sess.resultsDefault(10), "Maximum number of results")
 	fs.BoolVar(&fuzzy, "fuzzy", true, "Enable fuzzy search")
 	fs.BoolVar(&stops, "stops", true, "Include stops and stations")
 	fs.BoolVar(&addresses, "addresses", true, "Include addresses")
@@ -260,24 +546,28 @@ func runLocations(args []string, out io.Writer, errOut io.Writer, client api.Cli
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
 	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
 
+	if sess.describe(fs) {
+		return exitOK
+	}
+
 	fs.Usage = func() {
-		printLocationsUsage(errOut)
+		printLocationsUsage(sess.errOut)
 	}
 	if err := fs.Parse(args); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
-		printLocationsUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printLocationsUsage(sess.errOut)
 		return exitUsage
 	}
 	if helpFlag {
-		printLocationsUsage(out)
+		printLocationsUsage(sess.out)
 		return exitOK
 	}
 	if query == "" && fs.NArg() > 0 {
 		query = fs.Arg(0)
 	}
 	if strings.TrimSpace(query) == "" {
-		_, _ = fmt.Fprintln(errOut, "missing --query")
-		printLocationsUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, "missing --query")
+		printLocationsUsage(sess.errOut)
 		return exitUsage
 	}
 
@@ -289,60 +579,86 @@ func runLocations(args []string, out io.Writer, errOut io.Writer, client api.Cli
 	values.Set("addresses", strconv.FormatBool(addresses))
 	values.Set("poi", strconv.FormatBool(poi))
 	if err := addParams(values, params); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		_, _ = fmt.Fprintln(sess.errOut, err)
 		return exitUsage
 	}
 
-	return runRequestWithFormatter(out, errOut, client, "/locations", values, mode, verbose, format.LocationsPlain)
+	return runRequestWithFormatter(sess, "/locations", values, format.LocationsTable)
 }
 
-func runDepartures(args []string, out io.Writer, errOut io.Writer, client api.Clienter, mode OutputMode, verbose bool) int {
+func runDepartures(args []string, sess *session) int {
 	fs := flag.NewFlagSet("departures", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
 	var (
-		stop      string
+		stops     stopList
 		when      string
 		duration  int
 		results   int
 		direction string
+		strict    bool
+		watch     time.Duration
+		follow    int
 		params    paramList
 		helpFlag  bool
 	)
 
-	fs.StringVar(&stop, "stop", "", "Stop/station id")
-	fs.StringVar(&when, "when", "", "Departure time (ISO 8601)")
+	fs.Var(&stops, "stop", "Stop/station id or name (repeatable)")
+	fs.StringVar(&when, "when", "", "Departure time (e.g. now, +30m, tomorrow 07:45 or ISO 8601)")
 	fs.IntVar(&duration, "duration", 0, "Search window in minutes")
-	fs.IntVar(&results, "results", 0, "Maximum number of results")
+	fs.IntVar(&results, "results", sess.resultsDefault(0), "Maximum number of results")
 	fs.StringVar(&direction, "direction", "", "Direction filter (station id)")
+	fs.BoolVar(&strict, "strict", false, "Fail if a stop name is ambiguous")
+	fs.DurationVar(&watch, "watch", 0, "Re-run every interval (e.g. 30s) until interrupted")
+	fs.IntVar(&follow, "follow", 0, "Print the rest of the trip of the Nth departure")
+	products := addProductFlags(fs)
 	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
 	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
 
+	if sess.describe(fs) {
+		return exitOK
+	}
+
 	fs.Usage = func() {
-		printDeparturesUsage(errOut)
+		printDeparturesUsage(sess.errOut)
 	}
 	if err := fs.Parse(args); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
-		printDeparturesUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printDeparturesUsage(sess.errOut)
 		return exitUsage
 	}
 	if helpFlag {
-		printDeparturesUsage(out)
+		printDeparturesUsage(sess.out)
 		return exitOK
 	}
-	if stop == "" && fs.NArg() > 0 {
-		stop = fs.Arg(0)
+	if len(stops) == 0 && strings.TrimSpace(fs.Arg(0)) != "" {
+		stops = stopList{fs.Arg(0)}
 	}
-	if strings.TrimSpace(stop) == "" {
-		_, _ = fmt.Fprintln(errOut, "missing --stop")
-		printDeparturesUsage(errOut)
+	if len(stops) == 0 {
+		_, _ = fmt.Fprintln(sess.errOut, "missing --stop")
+		printDeparturesUsage(sess.errOut)
+		return exitUsage
+	}
+	expanded, ok := expandStops(sess, stops)
+	if !ok {
+		return exitUsage
+	}
+	switch {
+	case follow < 0:
+		_, _ = fmt.Fprintln(sess.errOut, "--follow must be positive")
+		return exitUsage
+	case follow > 0 && watch > 0:
+		_, _ = fmt.Fprintln(sess.errOut, "--follow cannot be combined with --watch")
+		return exitUsage
+	case len(expanded) > 1 && watch > 0:
+		_, _ = fmt.Fprintln(sess.errOut, "--watch supports a single --stop")
 		return exitUsage
 	}
 
 	values := url.Values{}
-	if when != "" {
-		values.Set("when", when)
+	if !setTime(sess, values, "when", when) {
+		return exitUsage
 	}
 	if duration > 0 {
 		values.Set("duration", strconv.Itoa(duration))
@@ -354,15 +670,48 @@ func runDepartures(args []string, out io.Writer, errOut io.Writer, client api.Cl
 		values.Set("direction", direction)
 	}
 	if err := addParams(values, params); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+	if !products.apply(sess, values) {
 		return exitUsage
 	}
 
-	path := "/stops/" + url.PathEscape(stop) + "/departures"
-	return runRequestWithFormatter(out, errOut, client, path, values, mode, verbose, format.StopoversPlain)
+	if len(expanded) > 1 {
+		data, complete := mergeDepartures(sess, expanded, values, results, strict)
+		if data == nil {
+			return exitError
+		}
+		var code int
+		if follow > 0 {
+			code = followTrip(sess, data, follow)
+		} else {
+			code = render(sess, data, format.MergedStopoversTable)
+		}
+		if code == exitOK && !complete {
+			return exitError
+		}
+		return code
+	}
+	stopID, err := resolveStop(sess, expanded[0], strict)
+	if err != nil {
+		return exitError
+	}
+	path := "/stops/" + url.PathEscape(stopID) + "/departures"
+	if watch > 0 {
+		return runWatch(sess, watch, path, values, format.StopoversTable)
+	}
+	if follow > 0 {
+		data, err := fetch(sess, path, values)
+		if err != nil {
+			return exitError
+		}
+		return followTrip(sess, data, follow)
+	}
+	return runRequestWithFormatter(sess, path, values, format.StopoversTable)
 }
 
-func runArrivals(args []string, out io.Writer, errOut io.Writer, client api.Clienter, mode OutputMode, verbose bool) int {
+func runArrivals(args []string, sess *session) int {
 	fs := flag.NewFlagSet("arrivals", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
@@ -372,43 +721,55 @@ func runArrivals(args []string, out io.Writer, errOut io.Writer, client api.Clie
 		duration  int
 		results   int
 		direction string
+		strict    bool
+		watch     time.Duration
 		params    paramList
 		helpFlag  bool
 	)
 
-	fs.StringVar(&stop, "stop", "", "Stop/station id")
-	fs.StringVar(&when, "when", "", "Arrival time (ISO 8601)")
+	fs.StringVar(&stop, "stop", "", "Stop/station id or name")
+	fs.StringVar(&when, "when", "", "Arrival time (e.g. now, +30m, tomorrow 07:45 or ISO 8601)")
 	fs.IntVar(&duration, "duration", 0, "Search window in minutes")
-	fs.IntVar(&results, "results", 0, "Maximum number of results")
+	fs.IntVar(&results, "results", sess.resultsDefault(0), "Maximum number of results")
 	fs.StringVar(&direction, "direction", "", "Direction filter (station id)")
+	fs.BoolVar(&strict, "strict", false, "Fail if a stop name is ambiguous")
+	fs.DurationVar(&watch, "watch", 0, "Re-run every interval (e.g. 30s) until interrupted")
+	products := addProductFlags(fs)
 	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
 	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
 
+	if sess.describe(fs) {
+		return exitOK
+	}
+
 	fs.Usage = func() {
-		printArrivalsUsage(errOut)
+		printArrivalsUsage(sess.errOut)
 	}
 	if err := fs.Parse(args); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
-		printArrivalsUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printArrivalsUsage(sess.errOut)
 		return exitUsage
 	}
 	if helpFlag {
-		printArrivalsUsage(out)
+		printArrivalsUsage(sess.out)
 		return exitOK
 	}
 	if stop == "" && fs.NArg() > 0 {
 		stop = fs.Arg(0)
 	}
 	if strings.TrimSpace(stop) == "" {
-		_, _ = fmt.Fprintln(errOut, "missing --stop")
-		printArrivalsUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, "missing --stop")
+		printArrivalsUsage(sess.errOut)
+		return exitUsage
+	}
+	if !expandAliases(sess, &stop) {
 		return exitUsage
 	}
 
 	values := url.Values{}
-	if when != "" {
-		values.Set("when", when)
+	if !setTime(sess, values, "when", when) {
+		return exitUsage
 	}
 	if duration > 0 {
 		values.Set("duration", strconv.Itoa(duration))
@@ -420,15 +781,25 @@ func runArrivals(args []string, out io.Writer, errOut io.Writer, client api.Clie
 		values.Set("direction", direction)
 	}
 	if err := addParams(values, params); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+	if !products.apply(sess, values) {
 		return exitUsage
 	}
 
-	path := "/stops/" + url.PathEscape(stop) + "/arrivals"
-	return runRequestWithFormatter(out, errOut, client, path, values, mode, verbose, format.StopoversPlain)
+	stopID, err := resolveStop(sess, stop, strict)
+	if err != nil {
+		return exitError
+	}
+	path := "/stops/" + url.PathEscape(stopID) + "/arrivals"
+	if watch > 0 {
+		return runWatch(sess, watch, path, values, format.StopoversTable)
+	}
+	return runRequestWithFormatter(sess, path, values, format.StopoversTable)
 }
 
-func runJourneys(args []string, out io.Writer, errOut io.Writer, client api.Clienter, mode OutputMode, verbose bool) int {
+func runJourneys(args []string, sess *session) int {
 	fs := flag.NewFlagSet("journeys", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
@@ -440,6 +811,11 @@ func runJourneys(args []string, out io.Writer, errOut io.Writer, client api.Clie
 		arrival   string
 		results   int
 		transfers int
+		legs      bool
+		later     string
+		earlier   string
+		pages     int
+		tokens    bool
 		params    paramList
 		helpFlag  bool
 	)
@@ -447,33 +823,58 @@ func runJourneys(args []string, out io.Writer, errOut io.Writer, client api.Clie
 	fs.StringVar(&from, "from", "", "Origin station/location id or name")
 	fs.StringVar(&to, "to", "", "Destination station/location id or name")
 	fs.StringVar(&via, "via", "", "Via station/location id or name")
-	fs.StringVar(&departure, "departure", "", "Departure time (ISO 8601)")
-	fs.StringVar(&arrival, "arrival", "", "Arrival time (ISO 8601)")
-	fs.IntVar(&results, "results", 0, "Maximum number of results")
+	fs.StringVar(&departure, "departure", "", "Departure time (e.g. now, +30m, tomorrow 07:45 or ISO 8601)")
+	fs.StringVar(&arrival, "arrival", "", "Arrival time (e.g. now, +30m, tomorrow 07:45 or ISO 8601)")
+	fs.IntVar(&results, "results", sess.resultsDefault(0), "Maximum number of results")
 	fs.IntVar(&transfers, "transfers", 0, "Maximum number of transfers")
+	fs.BoolVar(&legs, "legs", false, "Show every leg of each journey")
+	fs.StringVar(&later, "later", "", "Page ref for later journeys (laterRef)")
+	fs.StringVar(&earlier, "earlier", "", "Page ref for earlier journeys (earlierRef)")
+	fs.IntVar(&pages, "pages", 1, "Number of pages to fetch, following laterRef")
+	fs.BoolVar(&tokens, "show-tokens", false, "Add the refresh_token column")
+	products := addProductFlags(fs)
 	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
 	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
 
+	if sess.describe(fs) {
+		return exitOK
+	}
+
 	fs.Usage = func() {
-		printJourneysUsage(errOut)
+		printJourneysUsage(sess.errOut)
 	}
 	if err := fs.Parse(args); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
-		printJourneysUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printJourneysUsage(sess.errOut)
 		return exitUsage
 	}
 	if helpFlag {
-		printJourneysUsage(out)
+		printJourneysUsage(sess.out)
 		return exitOK
 	}
 	if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
-		_, _ = fmt.Fprintln(errOut, "--from and --to are required")
-		printJourneysUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, "--from and --to are required")
+		printJourneysUsage(sess.errOut)
 		return exitUsage
 	}
 	if departure != "" && arrival != "" {
-		_, _ = fmt.Fprintln(errOut, "--departure and --arrival are mutually exclusive")
+		_, _ = fmt.Fprintln(sess.errOut, "--departure and --arrival are mutually exclusive")
+		return exitUsage
+	}
+	if later != "" && earlier != "" {
+		_, _ = fmt.Fprintln(sess.errOut, "--later and --earlier are mutually exclusive")
+		return exitUsage
+	}
+	if pages < 1 {
+		_, _ = fmt.Fprintln(sess.errOut, "--pages must be at least 1")
+		return exitUsage
+	}
+	if pages > 1 && earlier != "" {
+		_, _ = fmt.Fprintln(sess.errOut, "--pages follows laterRef and cannot be combined with --earlier")
+		return exitUsage
+	}
+	if !expandAliases(sess, &from, &to, &via) {
 		return exitUsage
 	}
 
@@ -483,11 +884,8 @@ func runJourneys(args []string, out io.Writer, errOut io.Writer, client api.Clie
 	if via != "" {
 		values.Set("via", via)
 	}
-	if departure != "" {
-		values.Set("departure", departure)
-	}
-	if arrival != "" {
-		values.Set("arrival", arrival)
+	if !setTime(sess, values, "departure", departure) || !setTime(sess, values, "arrival", arrival) {
+		return exitUsage
 	}
 	if results > 0 {
 		values.Set("results", strconv.Itoa(results))
@@ -495,49 +893,488 @@ func runJourneys(args []string, out io.Writer, errOut io.Writer, client api.Clie
 	if transfers > 0 {
 		values.Set("transfers", strconv.Itoa(transfers))
 	}
+	if later != "" {
+		values.Set("laterThan", later)
+	}
+	if earlier != "" {
+		values.Set("earlierThan", earlier)
+	}
 	if err := addParams(values, params); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+	if !products.apply(sess, values) {
+		return exitUsage
+	}
+
+	data, err := fetchJourneyPages(sess, values, pages)
+	if err != nil {
+		return exitError
+	}
+	if earlierRef, laterRef, err := format.JourneyRefs(data); err == nil {
+		if earlierRef != "" {
+			_, _ = fmt.Fprintf(sess.errOut, "earlierRef: %s\n", earlierRef)
+		}
+		if laterRef != "" {
+			_, _ = fmt.Fprintf(sess.errOut, "laterRef: %s\n", laterRef)
+		}
+	}
+
+	opts := format.JourneyOptions{Tokens: tokens}
+	formatter := func(data []byte) (format.Table, error) {
+		if legs {
+			return format.JourneyLegsTable(data, opts)
+		}
+		return format.JourneysTable(data, opts)
+	}
+	return render(sess, data, formatter)
+}
+
+func runJourney(args []string, sess *session) int {
+	if len(args) == 0 {
+		printJourneyUsage(sess.errOut)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "--help" {
+		printJourneyUsage(sess.out)
+		return exitOK
+	}
+	if args[0] != "refresh" {
+		_, _ = fmt.Fprintf(sess.errOut, "unknown journey command: %s\n", args[0])
+		printJourneyUsage(sess.errOut)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("journey refresh", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		token    string
+		params   paramList
+		helpFlag bool
+	)
+
+	fs.StringVar(&token, "token", "", "Journey refresh token")
+	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
+	fs.BoolVar(&helpFlag, "help", false, "Show help")
+	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
+
+	if sess.describe(fs) {
+		return exitOK
+	}
+
+	fs.Usage = func() {
+		printJourneyUsage(sess.errOut)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printJourneyUsage(sess.errOut)
+		return exitUsage
+	}
+	if helpFlag {
+		printJourneyUsage(sess.out)
+		return exitOK
+	}
+	if token == "" && fs.NArg() > 0 {
+		token = fs.Arg(0)
+	}
+	if strings.TrimSpace(token) == "" {
+		_, _ = fmt.Fprintln(sess.errOut, "missing --token")
+		printJourneyUsage(sess.errOut)
+		return exitUsage
+	}
+
+	values := url.Values{}
+	if err := addParams(values, params); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+
+	path := "/journeys/" + url.PathEscape(token)
+	return runRequestWithFormatter(sess, path, values, func(data []byte) (format.Table, error) {
+		return format.RefreshedJourneyTable(data, format.JourneyOptions{})
+	})
+}
+
+// fetchJourneyPages fetches up to pages /journeys responses, following
+// laterRef, and merges them into one deduplicated response.
+func fetchJourneyPages(sess *session, values url.Values, pages int) ([]byte, error) {
+	data, err := fetch(sess, "/journeys", values)
+	if err != nil || pages == 1 {
+		return data, err
+	}
+	collected := [][]byte{data}
+	for len(collected) < pages {
+		_, laterRef, err := format.JourneyRefs(data)
+		if err != nil {
+			_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
+			return nil, err
+		}
+		if laterRef == "" {
+			break
+		}
+		values.Del("earlierThan")
+		values.Set("laterThan", laterRef)
+		data, err = fetch(sess, "/journeys", values)
+		if err != nil {
+			return nil, err
+		}
+		collected = append(collected, data)
+	}
+	merged, err := format.MergeJourneys(collected)
+	if err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
+		return nil, err
+	}
+	return merged, nil
+}
+
+func runNearby(args []string, sess *session) int {
+	fs := flag.NewFlagSet("nearby", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		lat      floatFlag
+		lon      floatFlag
+		near     string
+		distance int
+		results  int
+		poi      bool
+		params   paramList
+		helpFlag bool
+	)
+
+	fs.Var(&lat, "lat", "Latitude")
+	fs.Var(&lon, "lon", "Longitude")
+	fs.StringVar(&near, "near", "", "Address or place to search around")
+	fs.IntVar(&distance, "distance", 0, "Maximum walking distance in metres")
+	fs.IntVar(&results, "results", sess.resultsDefault(8), "Maximum number of results")
+	fs.BoolVar(&poi, "poi", false, "Include points of interest")
+	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
+	fs.BoolVar(&helpFlag, "help", false, "Show help")
+	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
+
+	if sess.describe(fs) {
+		return exitOK
+	}
+
+	fs.Usage = func() {
+		printNearbyUsage(sess.errOut)
+	}
+	if err := fs.Parse(args); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printNearbyUsage(sess.errOut)
+		return exitUsage
+	}
+	if helpFlag {
+		printNearbyUsage(sess.out)
+		return exitOK
+	}
+	if near == "" && fs.NArg() > 0 {
+		near = fs.Arg(0)
+	}
+	switch {
+	case strings.TrimSpace(near) != "" && (lat.set || lon.set):
+		_, _ = fmt.Fprintln(sess.errOut, "--near cannot be combined with --lat and --lon")
+		return exitUsage
+	case strings.TrimSpace(near) == "" && (!lat.set || !lon.set):
+		_, _ = fmt.Fprintln(sess.errOut, "--lat and --lon, or --near, are required")
+		printNearbyUsage(sess.errOut)
+		return exitUsage
+	}
+	if distance < 0 {
+		_, _ = fmt.Fprintln(sess.errOut, "--distance must not be negative")
 		return exitUsage
 	}
 
-	return runRequestWithFormatter(out, errOut, client, "/journeys", values, mode, verbose, format.JourneysPlain)
+	values := url.Values{}
+	if results > 0 {
+		values.Set("results", strconv.Itoa(results))
+	}
+	if distance > 0 {
+		values.Set("distance", strconv.Itoa(distance))
+	}
+	values.Set("poi", strconv.FormatBool(poi))
+	if err := addParams(values, params); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+
+	if near != "" {
+		latitude, longitude, err := geocode(sess, near)
+		if err != nil {
+			return exitError
+		}
+		lat.value, lon.value = latitude, longitude
+	}
+	values.Set("latitude", formatFloatArg(lat.value))
+	values.Set("longitude", formatFloatArg(lon.value))
+	return runRequestWithFormatter(sess, "/locations/nearby", values, format.NearbyTable)
 }
 
-func runTrip(args []string, out io.Writer, errOut io.Writer, client api.Clienter, mode OutputMode, verbose bool) int {
+func runReachable(args []string, sess *session) int {
+	fs := flag.NewFlagSet("reachable", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		address      string
+		lat          floatFlag
+		lon          floatFlag
+		maxDuration  int
+		maxTransfers int
+		when         string
+		bucket       int
+		params       paramList
+		helpFlag     bool
+	)
+
+	fs.StringVar(&address, "address", "", "Address to start from")
+	fs.Var(&lat, "lat", "Latitude")
+	fs.Var(&lon, "lon", "Longitude")
+	fs.IntVar(&maxDuration, "max-duration", 0, "Maximum travel time in minutes")
+	fs.IntVar(&maxTransfers, "max-transfers", -1, "Maximum number of transfers")
+	fs.StringVar(&when, "when", "", "Departure time (e.g. now, +30m, tomorrow 07:45 or ISO 8601)")
+	fs.IntVar(&bucket, "bucket", 10, "Minutes per travel-time group in human output")
+	products := addProductFlags(fs)
+	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
+	fs.BoolVar(&helpFlag, "help", false, "Show help")
+	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
+
+	if sess.describe(fs) {
+		return exitOK
+	}
+
+	fs.Usage = func() {
+		printReachableUsage(sess.errOut)
+	}
+	if err := fs.Parse(args); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printReachableUsage(sess.errOut)
+		return exitUsage
+	}
+	if helpFlag {
+		printReachableUsage(sess.out)
+		return exitOK
+	}
+	if address == "" && fs.NArg() > 0 {
+		address = fs.Arg(0)
+	}
+	switch {
+	case lat.set != lon.set:
+		_, _ = fmt.Fprintln(sess.errOut, "--lat and --lon must be given together")
+		return exitUsage
+	case strings.TrimSpace(address) == "" && !lat.set:
+		_, _ = fmt.Fprintln(sess.errOut, "--address or --lat and --lon are required")
+		printReachableUsage(sess.errOut)
+		return exitUsage
+	case maxDuration < 0:
+		_, _ = fmt.Fprintln(sess.errOut, "--max-duration must not be negative")
+		return exitUsage
+	case bucket <= 0:
+		_, _ = fmt.Fprintln(sess.errOut, "--bucket must be positive")
+		return exitUsage
+	}
+
+	values := url.Values{}
+	if maxDuration > 0 {
+		values.Set("maxDuration", strconv.Itoa(maxDuration))
+	}
+	if maxTransfers >= 0 {
+		values.Set("maxTransfers", strconv.Itoa(maxTransfers))
+	}
+	if !setTime(sess, values, "when", when) {
+		return exitUsage
+	}
+	if err := addParams(values, params); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+	if !products.apply(sess, values) {
+		return exitUsage
+	}
+
+	if !lat.set {
+		latitude, longitude, err := geocode(sess, address)
+		if err != nil {
+			return exitError
+		}
+		lat.value, lon.value = latitude, longitude
+	}
+	if strings.TrimSpace(address) == "" {
+		address = formatFloatArg(lat.value) + "," + formatFloatArg(lon.value)
+	}
+	values.Set("address", address)
+	values.Set("latitude", formatFloatArg(lat.value))
+	values.Set("longitude", formatFloatArg(lon.value))
+
+	data, err := fetch(sess, "/stops/reachable-from", values)
+	if err != nil {
+		return exitError
+	}
+	if sess.mode != OutputHuman {
+		return render(sess, data, format.ReachableTable)
+	}
+	table, err := format.ReachableTable(data)
+	if err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
+		return exitError
+	}
+	groups := format.ReachableGroups(table, bucket)
+	if len(groups) == 0 {
+		return writeTable(sess, table)
+	}
+	for i, group := range groups {
+		if i > 0 {
+			_, _ = fmt.Fprintln(sess.out)
+		}
+		_, _ = fmt.Fprintf(sess.out, "%s:\n", group.Title)
+		if code := writeTable(sess, group.Table); code != exitOK {
+			return code
+		}
+	}
+	return exitOK
+}
+
+// geocode looks up the coordinates of a free-text address or place via
+// /locations and prints the chosen location to stderr.
+func geocode(sess *session, query string) (float64, float64, error) {
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("results", "5")
+	values.Set("addresses", "true")
+	values.Set("poi", "true")
+	values.Set("stops", "true")
+	data, err := fetch(sess, "/locations", values)
+	if err != nil {
+		return 0, 0, err
+	}
+	var locations []format.Location
+	if err := json.Unmarshal(data, &locations); err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "geocode %q: %v\n", query, err)
+		return 0, 0, err
+	}
+	for _, loc := range locations {
+		lat, lon := loc.Coordinates()
+		if lat == nil || lon == nil {
+			continue
+		}
+		name := loc.Name
+		if name == "" {
+			name = loc.ID
+		}
+		_, _ = fmt.Fprintf(sess.errOut, "using location %s (%s, %s)\n", name, formatFloatArg(*lat), formatFloatArg(*lon))
+		return *lat, *lon, nil
+	}
+	err = fmt.Errorf("no location found for %q", query)
+	_, _ = fmt.Fprintln(sess.errOut, err)
+	return 0, 0, err
+}
+
+func runStop(args []string, sess *session) int {
+	fs := flag.NewFlagSet("stop", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		stop     string
+		lines    bool
+		strict   bool
+		params   paramList
+		helpFlag bool
+	)
+
+	fs.StringVar(&stop, "stop", "", "Stop/station id or name")
+	fs.BoolVar(&lines, "lines", true, "Include the lines serving the stop")
+	fs.BoolVar(&strict, "strict", false, "Fail if a stop name is ambiguous")
+	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
+	fs.BoolVar(&helpFlag, "help", false, "Show help")
+	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
+
+	if sess.describe(fs) {
+		return exitOK
+	}
+
+	fs.Usage = func() {
+		printStopUsage(sess.errOut)
+	}
+	if err := fs.Parse(args); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printStopUsage(sess.errOut)
+		return exitUsage
+	}
+	if helpFlag {
+		printStopUsage(sess.out)
+		return exitOK
+	}
+	if stop == "" && fs.NArg() > 0 {
+		stop = fs.Arg(0)
+	}
+	if strings.TrimSpace(stop) == "" {
+		_, _ = fmt.Fprintln(sess.errOut, "missing --stop")
+		printStopUsage(sess.errOut)
+		return exitUsage
+	}
+	if !expandAliases(sess, &stop) {
+		return exitUsage
+	}
+
+	values := url.Values{}
+	values.Set("linesOfStops", strconv.FormatBool(lines))
+	if err := addParams(values, params); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+
+	stopID, err := resolveStop(sess, stop, strict)
+	if err != nil {
+		return exitError
+	}
+	return runRequestWithFormatter(sess, "/stops/"+url.PathEscape(stopID), values, format.StopTable)
+}
+
+func runTrip(args []string, sess *session) int {
 	fs := flag.NewFlagSet("trip", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
 	var (
 		tripID   string
 		lineName string
+		watch    time.Duration
 		params   paramList
 		helpFlag bool
 	)
 
 	fs.StringVar(&tripID, "id", "", "Trip id")
 	fs.StringVar(&lineName, "line-name", "", "Line name filter")
+	fs.DurationVar(&watch, "watch", 0, "Re-run every interval (e.g. 30s) until interrupted")
 	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
 	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
 
+	if sess.describe(fs) {
+		return exitOK
+	}
+
 	fs.Usage = func() {
-		printTripUsage(errOut)
+		printTripUsage(sess.errOut)
 	}
 	if err := fs.Parse(args); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
-		printTripUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printTripUsage(sess.errOut)
 		return exitUsage
 	}
 	if helpFlag {
-		printTripUsage(out)
+		printTripUsage(sess.out)
 		return exitOK
 	}
 	if tripID == "" && fs.NArg() > 0 {
 		tripID = fs.Arg(0)
 	}
 	if strings.TrimSpace(tripID) == "" {
-		_, _ = fmt.Fprintln(errOut, "missing --id")
-		printTripUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, "missing --id")
+		printTripUsage(sess.errOut)
+		return exitUsage
+	}
+	if !expandAliases(sess, &tripID) {
 		return exitUsage
 	}
 
@@ -546,15 +1383,22 @@ func runTrip(args []string, out io.Writer, errOut io.Writer, client api.Clienter
 		values.Set("lineName", lineName)
 	}
 	if err := addParams(values, params); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		_, _ = fmt.Fprintln(sess.errOut, err)
 		return exitUsage
 	}
 
+	if sess.mode == OutputGeoJSON && !values.Has("polyline") {
+		values.Set("polyline", "true")
+	}
+
 	path := "/trips/" + url.PathEscape(tripID)
-	return runRequestWithFormatter(out, errOut, client, path, values, mode, verbose, format.TripPlain)
+	if watch > 0 {
+		return runWatch(sess, watch, path, values, format.TripTable)
+	}
+	return runRequestWithFormatter(sess, path, values, format.TripTable)
 }
 
-func runRadar(args []string, out io.Writer, errOut io.Writer, client api.Clienter, mode OutputMode, verbose bool) int {
+func runRadar(args []string, sess *session) int {
 	fs := flag.NewFlagSet("radar", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
@@ -565,6 +1409,7 @@ func runRadar(args []string, out io.Writer, errOut io.Writer, client api.Cliente
 		east     floatFlag
 		results  int
 		duration int
+		watch    time.Duration
 		params   paramList
 		helpFlag bool
 	)
@@ -573,27 +1418,33 @@ func runRadar(args []string, out io.Writer, errOut io.Writer, client api.Cliente
 	fs.Var(&south, "south", "Southern latitude")
 	fs.Var(&west, "west", "Western longitude")
 	fs.Var(&east, "east", "Eastern longitude")
-	fs.IntVar(&results, "results", 0, "Maximum number of results")
+	fs.IntVar(&results, "results", sess.resultsDefault(0), "Maximum number of results")
 	fs.IntVar(&duration, "duration", 0, "Timespan in seconds")
+	fs.DurationVar(&watch, "watch", 0, "Re-run every interval (e.g. 30s) until interrupted")
+	products := addProductFlags(fs)
 	fs.Var(&params, "param", "Extra query param key=value (repeatable)")
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
 	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
 
+	if sess.describe(fs) {
+		return exitOK
+	}
+
 	fs.Usage = func() {
-		printRadarUsage(errOut)
+		printRadarUsage(sess.errOut)
 	}
 	if err := fs.Parse(args); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
-		printRadarUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printRadarUsage(sess.errOut)
 		return exitUsage
 	}
 	if helpFlag {
-		printRadarUsage(out)
+		printRadarUsage(sess.out)
 		return exitOK
 	}
 	if !north.set || !south.set || !west.set || !east.set {
-		_, _ = fmt.Fprintln(errOut, "--north, --south, --west, and --east are required")
-		printRadarUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, "--north, --south, --west, and --east are required")
+		printRadarUsage(sess.errOut)
 		return exitUsage
 	}
 
@@ -609,14 +1460,20 @@ func runRadar(args []string, out io.Writer, errOut io.Writer, client api.Cliente
 		values.Set("duration", strconv.Itoa(duration))
 	}
 	if err := addParams(values, params); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+	if !products.apply(sess, values) {
 		return exitUsage
 	}
 
-	return runRequestWithFormatter(out, errOut, client, "/radar", values, mode, verbose, format.RadarPlain)
+	if watch > 0 {
+		return runWatch(sess, watch, "/radar", values, format.RadarTable)
+	}
+	return runRequestWithFormatter(sess, "/radar", values, format.RadarTable)
 }
 
-func runRequest(args []string, out io.Writer, errOut io.Writer, client api.Clienter, mode OutputMode, verbose bool) int {
+func runRequest(args []string, sess *session) int {
 	fs := flag.NewFlagSet("request", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
@@ -631,82 +1488,289 @@ func runRequest(args []string, out io.Writer, errOut io.Writer, client api.Clien
 	fs.BoolVar(&helpFlag, "help", false, "Show help")
 	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
 
+	if sess.describe(fs) {
+		return exitOK
+	}
+
 	fs.Usage = func() {
-		printRequestUsage(errOut)
+		printRequestUsage(sess.errOut)
 	}
 	if err := fs.Parse(args); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
-		printRequestUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printRequestUsage(sess.errOut)
 		return exitUsage
 	}
 	if helpFlag {
-		printRequestUsage(out)
+		printRequestUsage(sess.out)
 		return exitOK
 	}
 	if path == "" && fs.NArg() > 0 {
 		path = fs.Arg(0)
 	}
 	if strings.TrimSpace(path) == "" {
-		_, _ = fmt.Fprintln(errOut, "missing --path")
-		printRequestUsage(errOut)
+		_, _ = fmt.Fprintln(sess.errOut, "missing --path")
+		printRequestUsage(sess.errOut)
 		return exitUsage
 	}
 
 	values := url.Values{}
 	if err := addParams(values, params); err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		_, _ = fmt.Fprintln(sess.errOut, err)
 		return exitUsage
 	}
 
-	return runRequestRaw(out, errOut, client, path, values, mode, verbose)
+	return runRequestRaw(sess, path, values)
 }
 
-func runRequestWithFormatter(out io.Writer, errOut io.Writer, client api.Clienter, path string, values url.Values, mode OutputMode, verbose bool, formatter func([]byte, bool) (string, error)) int {
-	data, err := fetch(errOut, client, path, values, verbose)
+func runCache(args []string, sess *session, dir string, ttl func(string) time.Duration) int {
+	if len(args) == 0 {
+		printCacheUsage(sess.errOut)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "--help" {
+		printCacheUsage(sess.out)
+		return exitOK
+	}
+	if dir == "" {
+		_, _ = fmt.Fprintln(sess.errOut, "cache dir unknown: set XDG_CACHE_HOME or HOME")
+		return exitError
+	}
+	switch args[0] {
+	case "stats":
+		stats, err := api.ReadCacheStats(dir, ttl)
+		if err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return exitError
+		}
+		switch sess.mode {
+		case OutputJSON:
+			data, err := json.Marshal(stats)
+			if err != nil {
+				_, _ = fmt.Fprintln(sess.errOut, err)
+				return exitError
+			}
+			writeJSON(sess.out, data)
+		case OutputHuman:
+			_, _ = fmt.Fprintf(sess.out, "dir:      %s\nentries:  %d\nexpired:  %d\nsize:     %d bytes\n", stats.Dir, stats.Entries, stats.Expired, stats.Bytes)
+		default:
+			t := format.Table{Columns: []format.Column{{Name: "dir"}, {Name: "entries", Kind: format.KindNumber}, {Name: "expired", Kind: format.KindNumber}, {Name: "bytes", Kind: format.KindNumber}}}
+			t.Rows = []format.Row{{Cells: []string{stats.Dir, strconv.Itoa(stats.Entries), strconv.Itoa(stats.Expired), strconv.FormatInt(stats.Bytes, 10)}}}
+			return writeTable(sess, t)
+		}
+		return exitOK
+	case "clear":
+		removed, err := api.ClearCache(dir)
+		if err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return exitError
+		}
+		if sess.mode == OutputHuman {
+			_, _ = fmt.Fprintf(sess.out, "removed %d entries\n", removed)
+		}
+		return exitOK
+	default:
+		_, _ = fmt.Fprintf(sess.errOut, "unknown cache command: %s\n", args[0])
+		printCacheUsage(sess.errOut)
+		return exitUsage
+	}
+}
+
+// resolveStop maps a stop name to its id via /locations. Numeric input is
+// returned unchanged. In strict mode several candidates without an exact
+// name match are an error instead of silently taking the first one.
+func resolveStop(sess *session, stop string, strict bool) (string, error) {
+	stop = strings.TrimSpace(stop)
+	if isNumeric(stop) {
+		return stop, nil
+	}
+	results := 1
+	if strict {
+		results = 5
+	}
+	values := url.Values{}
+	values.Set("query", stop)
+	values.Set("results", strconv.Itoa(results))
+	values.Set("stops", "true")
+	values.Set("addresses", "false")
+	values.Set("poi", "false")
+	data, err := fetch(sess, "/locations", values)
+	if err != nil {
+		return "", err
+	}
+	var locations []format.Location
+	if err := json.Unmarshal(data, &locations); err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "resolve stop %q: %v\n", stop, err)
+		return "", err
+	}
+	var candidates []format.Location
+	for _, loc := range locations {
+		if loc.ID != "" {
+			candidates = append(candidates, loc)
+		}
+	}
+	if len(candidates) == 0 {
+		err := fmt.Errorf("no stop found for %q", stop)
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return "", err
+	}
+	chosen := candidates[0]
+	if strict && len(candidates) > 1 {
+		exact := -1
+		for i, loc := range candidates {
+			if strings.EqualFold(loc.Name, stop) {
+				exact = i
+				break
+			}
+		}
+		if exact < 0 {
+			_, _ = fmt.Fprintf(sess.errOut, "ambiguous stop %q, candidates:\n", stop)
+			for _, loc := range candidates {
+				_, _ = fmt.Fprintf(sess.errOut, "  %s\t%s\n", loc.ID, loc.Name)
+			}
+			return "", fmt.Errorf("ambiguous stop %q", stop)
+		}
+		chosen = candidates[exact]
+	}
+	_, _ = fmt.Fprintf(sess.errOut, "using stop %s (%s)\n", chosen.ID, chosen.Name)
+	return chosen.ID, nil
+}
+
+func isNumeric(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func runRequestWithFormatter(sess *session, path string, values url.Values, formatter func([]byte) (format.Table, error)) int {
+	data, err := fetch(sess, path, values)
 	if err != nil {
 		return exitError
 	}
-	if mode == OutputJSON {
-		writeJSON(out, data)
+	return render(sess, data, formatter)
+}
+
+// render writes a response body in the session's output mode.
+func render(sess *session, data []byte, formatter func([]byte) (format.Table, error)) int {
+	if sess.mode == OutputJSON {
+		writeJSON(sess.out, data)
 		return exitOK
 	}
-	withHeader := mode == OutputHuman
-	formatted, err := formatter(data, withHeader)
+	table, err := formatter(data)
 	if err != nil {
-		_, _ = fmt.Fprintf(errOut, "formatting error: %v\n", err)
+		_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
 		return exitError
 	}
+	return writeTable(sess, table)
+}
+
+// writeTable writes the --fields selection of t in the session's output
+// mode; JSON is handled by the callers since it does not go through a
+// table.
+func writeTable(sess *session, t format.Table) int {
+	t, err := t.Select(sess.fields)
+	if err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		return exitUsage
+	}
+	t = sess.formatTimes(t)
+	var formatted string
+	switch sess.mode {
+	case OutputTemplate:
+		formatted, err = t.Template(sess.template)
+		if err != nil {
+			_, _ = fmt.Fprintf(sess.errOut, "template error: %v\n", err)
+			return exitError
+		}
+	case OutputHuman:
+		formatted = t.Render(sess.table)
+	case OutputCSV:
+		formatted = t.CSV(sess.header)
+	case OutputTSV:
+		formatted = t.TSV(sess.header)
+	case OutputNDJSON:
+		formatted = t.NDJSON()
+	case OutputGeoJSON:
+		formatted = t.GeoJSON()
+	case OutputICS:
+		formatted, err = t.ICS(time.Now())
+		if err != nil {
+			_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
+			return exitError
+		}
+	default:
+		formatted = t.Plain(false)
+	}
 	if formatted != "" {
-		_, _ = fmt.Fprint(out, formatted)
+		_, _ = fmt.Fprint(sess.out, formatted)
 	}
 	return exitOK
 }
 
-func runRequestRaw(out io.Writer, errOut io.Writer, client api.Clienter, path string, values url.Values, mode OutputMode, verbose bool) int {
-	data, err := fetch(errOut, client, path, values, verbose)
+// formatTimes applies --time-format and --tz to the time columns of t.
+func (s *session) formatTimes(t format.Table) format.Table {
+	opts := s.times
+	opts.Now = time.Now()
+	return t.FormatTimes(opts)
+}
+
+// parseTimeFormat maps --time-format to format options. Without a value,
+// human output shows "08:00 (in 5 min)" and every other mode keeps ISO.
+func parseTimeFormat(value string, mode OutputMode) (format.TimeOptions, bool) {
+	switch value {
+	case "":
+		if mode == OutputHuman {
+			return format.TimeOptions{Style: format.TimeLayout, Layout: "15:04", Relative: true}, true
+		}
+		return format.TimeOptions{Style: format.TimeISO}, true
+	case "iso":
+		return format.TimeOptions{Style: format.TimeISO}, true
+	case "hh:mm":
+		return format.TimeOptions{Style: format.TimeLayout, Layout: "15:04"}, true
+	case "relative":
+		return format.TimeOptions{Style: format.TimeRelative}, true
+	}
+	// A layout without any element formats every time as itself; use a
+	// time other than Go's reference one, which every layout reproduces.
+	probe := time.Date(1999, 11, 28, 21, 37, 48, 0, time.UTC)
+	if probe.Format(value) == value {
+		return format.TimeOptions{}, false
+	}
+	return format.TimeOptions{Style: format.TimeLayout, Layout: value}, true
+}
+
+func runRequestRaw(sess *session, path string, values url.Values) int {
+	data, err := fetch(sess, path, values)
 	if err != nil {
 		return exitError
 	}
-	if mode == OutputPlain {
-		_, _ = fmt.Fprint(out, string(data))
+	if sess.mode != OutputHuman && sess.mode != OutputJSON && sess.mode != OutputTemplate {
+		_, _ = fmt.Fprint(sess.out, string(data))
 		if len(data) == 0 || data[len(data)-1] != '\n' {
-			_, _ = fmt.Fprintln(out)
+			_, _ = fmt.Fprintln(sess.out)
 		}
 		return exitOK
 	}
-	writeJSON(out, data)
+	writeJSON(sess.out, data)
 	return exitOK
 }
 
-func fetch(errOut io.Writer, client api.Clienter, path string, values url.Values, verbose bool) ([]byte, error) {
-	if verbose {
-		if urlStr, err := client.URL(path, values); err == nil {
-			_, _ = fmt.Fprintf(errOut, "GET %s\n", urlStr)
+func fetch(sess *session, path string, values url.Values) ([]byte, error) {
+	if sess.verbose {
+		if urlStr, err := sess.client.URL(path, values); err == nil {
+			_, _ = fmt.Fprintf(sess.errOut, "GET %s\n", urlStr)
 		}
 	}
-	data, err := client.Get(context.Background(), path, values)
+	data, err := sess.client.Get(sess.ctx, path, values)
 	if err != nil {
-		_, _ = fmt.Fprintln(errOut, err)
+		if sess.ctx.Err() == nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+		}
 		return nil, err
 	}
 	return data, nil
@@ -730,6 +1794,29 @@ func envOrDefault(getenv func(string) string, key, fallback string) string {
 	return value
 }
 
+// cacheDir returns $XDG_CACHE_HOME/dbrest, falling back to ~/.cache/dbrest.
+// An empty result disables caching.
+func cacheDir(getenv func(string) string) string {
+	if base := envOrDefault(getenv, "XDG_CACHE_HOME", ""); base != "" {
+		return filepath.Join(base, "dbrest")
+	}
+	if home := envOrDefault(getenv, "HOME", ""); home != "" {
+		return filepath.Join(home, ".cache", "dbrest")
+	}
+	return ""
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func formatFloatArg(value float64) string {
 	return strconv.FormatFloat(value, 'f', 6, 64)
 }
@@ -742,12 +1829,20 @@ USAGE:
 
 COMMANDS:
   locations   Search for stations/places/addresses
+  nearby      List stops near coordinates or an address
+  reachable   List stops reachable within a travel time
+  stop        Show details of a stop
   departures  List departures for a stop
   arrivals    List arrivals for a stop
   journeys    Find journeys between two locations
+  journey     Refresh a journey by its refresh token
   trip        Fetch a trip by id
   radar       List vehicle movements in a bounding box
   request     Perform a raw GET request
+  cache       Show or clear the response cache
+  config      Show the effective configuration
+  alias       Save stop ids as @aliases
+  completion  Print a shell completion script (bash, zsh, fish)
   help        Show command help
 
 GLOBAL FLAGS:
@@ -755,17 +1850,61 @@ GLOBAL FLAGS:
       --version        Show version
       --json           Output raw JSON
       --plain          Output stable, line-based text
+      --output         Output mode: human, plain, json, csv, tsv, ndjson,
+                       geojson or ics
+      --no-header      Omit the header row of csv/tsv output
+      --fields         Comma-separated columns to print, in order
+                       (see 'dbrest help fields <command>')
+      --template       Go template printed once per record
+      --template-file  Read the template from a file
       --base-url       API base URL (default: https://v6.db.transport.rest)
       --timeout        HTTP timeout (default: 10s)
-      --verbose        Print request details to stderr
+      --retries        Retries for transient failures (default: 2)
+      --no-cache       Bypass the response cache
+      --cache-ttl      Cache lifetime for all endpoints (default: per endpoint)
+      --profile        Config profile to use
+      --tz             Time zone for --when/--departure/--arrival
+                       (default: Europe/Berlin) and, when given, for
+                       time columns
+      --time-format    Time columns: iso, hh:mm, relative or a Go layout
+                       (default: hh:mm plus "in 5 min" for human output,
+                       iso otherwise)
+      --verbose        Print request details and retries to stderr
 
 OUTPUT MODES:
+  (default) Aligned table fitted to the terminal, coloured on a TTY
   --json   Raw API response JSON
   --plain  Tab-separated columns, no header (request prints raw JSON)
+  --output csv|tsv
+           RFC 4180 quoted records with a header row (--no-header drops
+           it); same columns as --plain
+  --output ndjson
+           One JSON object per row, keyed by the --plain column names
+  --output geojson
+           FeatureCollection of points for locations, nearby, reachable
+           (coloured by travel time), stop, radar and trip stops, plus
+           the trip route as a LineString
+  --output ics
+           iCalendar file with one event per journey (per leg with
+           journeys --legs and journey refresh), in Europe/Berlin time
+  --template '{{.Line}} → {{.Direction}} in {{.MinutesUntil}}m'
+           One line per record; helpers: time, delay, pad, padLeft
 
 ENV:
+  DBREST_PROFILE    Select the config profile
   DBREST_BASE_URL   Override the API base URL
   DBREST_TIMEOUT    Override the HTTP timeout
+  DBREST_RETRIES    Override the retry count
+  NO_COLOR          Disable coloured table output
+  COLUMNS           Override the detected terminal width
+  XDG_CACHE_HOME    Cache location (default: ~/.cache, entries in dbrest/)
+  XDG_CONFIG_HOME   Config location (default: ~/.config, file dbrest/config.toml)
+
+CONFIG:
+  Profiles in config.toml set base_url, timeout, output, results and
+  products. Precedence: flags > env > profile > defaults.
+  See 'dbrest help config'. Aliases saved with 'dbrest alias add' can be
+  used as @name for --stop, --from, --to, --via and trip ids.
 
 EXAMPLES:
   dbrest locations Berlin
@@ -794,40 +1933,170 @@ EXAMPLE:
   dbrest locations Berlin`)
 }
 
+func printNearbyUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest nearby --lat <lat> --lon <lon> [flags]
+  dbrest nearby --near <address> [flags]
+  dbrest nearby <address> [flags]
+
+FLAGS:
+  --lat          Latitude (required without --near)
+  --lon          Longitude (required without --near)
+  --near         Address or place to search around, looked up via /locations
+  --distance     Maximum walking distance in metres
+  --results      Maximum number of results (default: 8)
+  --poi          Include points of interest
+  --param        Extra query param key=value (repeatable)
+  -h, --help     Show help
+
+NOTE:
+  Results are sorted by distance, nearest first. --near prints the
+  location it used to stderr. --plain prints the locations columns: id,
+  name, type, latitude, longitude, distance_m.
+
+EXAMPLE:
+  dbrest nearby --lat 52.52 --lon 13.40 --distance 500 --results 10
+  dbrest nearby --near "Alexanderplatz"`)
+}
+
+func printReachableUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest reachable --address <address> [flags]
+  dbrest reachable --lat <lat> --lon <lon> [flags]
+  dbrest reachable <address> [flags]
+
+FLAGS:
+  --address      Address to start from, looked up via /locations unless
+                 --lat and --lon are given
+  --lat          Latitude (required without --address)
+  --lon          Longitude (required without --address)
+  --max-duration
+                 Maximum travel time in minutes
+  --max-transfers
+                 Maximum number of transfers
+  --when         Departure time: now, +30m, in 2h, 18:30, tomorrow 07:45,
+                 mon 08:00, morgen 7:45 or ISO 8601
+  --bucket       Minutes per travel-time group in human output (default: 10)
+  --products     Only these products, comma-separated: ice, ic/ec, re/ire,
+                 rb, s, u, bus, ferry, str/tram, taxi or API names
+  --exclude-products
+                 Leave out these products, comma-separated
+  --param        Extra query param key=value (repeatable)
+  -h, --help     Show help
+
+NOTE:
+  Human output groups the stops by travel time ("up to 10 min",
+  "10-20 min", ...). --output geojson colours the points from green
+  (fastest) to red (slowest). --plain prints the columns: duration, id,
+  name, latitude, longitude.
+
+EXAMPLE:
+  dbrest reachable --address "Alexanderplatz" --max-duration 30 --max-transfers 1
+  dbrest --output geojson reachable --lat 52.52 --lon 13.40 --max-duration 20`)
+}
+
+func printStopUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest stop --stop <id|name> [flags]
+  dbrest stop <id|name> [flags]
+
+FLAGS:
+  --stop         Stop/station id, name or @alias (required)
+  --lines        Include the lines serving the stop (default: true)
+  --strict       Fail if a stop name is ambiguous
+  --param        Extra query param key=value (repeatable)
+  -h, --help     Show help
+
+NOTE:
+  Prints the stop's id, name, type, coordinates, products, lines,
+  facilities and accessibility information, where the API has them.
+  --plain prints one line: id, name, type, latitude, longitude, products,
+  lines, facilities, accessibility. Facilities are key=value pairs.
+  See 'dbrest help fields stop' for station metadata fields.
+
+EXAMPLE:
+  dbrest stop 8011160
+  dbrest --fields name,lines stop "Berlin Hbf"`)
+}
+
 func printDeparturesUsage(out io.Writer) {
 	_, _ = fmt.Fprintln(out, `USAGE:
-  dbrest departures --stop <id> [flags]
-  dbrest departures <id> [flags]
+  dbrest departures --stop <id|name> [flags]
+  dbrest departures <id|name> [flags]
 
 FLAGS:
-  --stop         Stop/station id (required)
-  --when         Departure time (ISO 8601)
+  --stop         Stop/station id, name or @alias (required; repeatable)
+  --when         Departure time: now, +30m, in 2h, 18:30, tomorrow 07:45,
+                 mon 08:00, morgen 7:45 or ISO 8601
   --duration     Search window in minutes
   --results      Maximum number of results
   --direction    Direction filter (station id)
+  --strict       Fail if a stop name is ambiguous
+  --watch        Re-run every interval (e.g. 30s) until Ctrl-C (one stop only)
+  --follow       Print the rest of the trip of the Nth departure (from 1)
+  --products     Only these products, comma-separated: ice, ic/ec, re/ire,
+                 rb, s, u, bus, ferry, str/tram, taxi or API names
+  --exclude-products
+                 Leave out these products, comma-separated
   --param        Extra query param key=value (repeatable)
   -h, --help     Show help
 
+NOTE:
+  Non-numeric stops are resolved via /locations; the chosen stop is
+  printed to stderr.
+  Several --stop flags (or a group alias, see 'dbrest help alias')
+  fetch all boards concurrently and print one board, sorted by real-time
+  departure, with a stop column; a trip serving several of the stops is
+  shown once, at its first departure. --results limits the whole board.
+  A stop that fails is reported and left out, and the exit code is 1.
+  --follow N fetches the trip of the Nth row via /trips/{id} and prints
+  its stops from this one on, with arrival_delay and departure_delay;
+  --fields then selects trip columns. The trip id of every row is in the
+  trip_id field.
+  --when is resolved in --tz; a weekday or a clock time alone means its
+  next occurrence, and --verbose prints the resolved time.
+  With --watch, human output is redrawn in place; --plain and --json
+  print only rows that changed (--json as one object per line).
+
 EXAMPLE:
-  dbrest departures 8011160 --results 5`)
+  dbrest departures --results 5 8011160
+  dbrest departures --stop 900100003 --stop 900003201 --stop @bus
+  dbrest departures --follow 1 8011160
+  dbrest departures --products ice,ic,re --exclude-products bus 8011160`)
 }
 
 func printArrivalsUsage(out io.Writer) {
 	_, _ = fmt.Fprintln(out, `USAGE:
-  dbrest arrivals --stop <id> [flags]
-  dbrest arrivals <id> [flags]
+  dbrest arrivals --stop <id|name> [flags]
+  dbrest arrivals <id|name> [flags]
 
 FLAGS:
-  --stop         Stop/station id (required)
-  --when         Arrival time (ISO 8601)
+  --stop         Stop/station id, name or @alias (required)
+  --when         Arrival time: now, +30m, in 2h, 18:30, tomorrow 07:45,
+                 mon 08:00, morgen 7:45 or ISO 8601
   --duration     Search window in minutes
   --results      Maximum number of results
   --direction    Direction filter (station id)
+  --strict       Fail if a stop name is ambiguous
+  --watch        Re-run every interval (e.g. 30s) until Ctrl-C
+  --products     Only these products, comma-separated: ice, ic/ec, re/ire,
+                 rb, s, u, bus, ferry, str/tram, taxi or API names
+  --exclude-products
+                 Leave out these products, comma-separated
   --param        Extra query param key=value (repeatable)
   -h, --help     Show help
 
+NOTE:
+  Non-numeric stops are resolved via /locations; the chosen stop is
+  printed to stderr.
+  --when is resolved in --tz; a weekday or a clock time alone means its
+  next occurrence, and --verbose prints the resolved time.
+  With --watch, human output is redrawn in place; --plain and --json
+  print only rows that changed (--json as one object per line).
+
 EXAMPLE:
-  dbrest arrivals 8011160 --when "2024-02-01T08:00:00+01:00"`)
+  dbrest arrivals --when "2024-02-01T08:00:00+01:00" 8011160
+  dbrest arrivals --when "tomorrow 07:45" 8011160`)
 }
 
 func printJourneysUsage(out io.Writer) {
@@ -835,18 +2104,53 @@ func printJourneysUsage(out io.Writer) {
   dbrest journeys --from <id|name> --to <id|name> [flags]
 
 FLAGS:
-  --from         Origin station/location id or name (required)
-  --to           Destination station/location id or name (required)
-  --via          Via station/location id or name
-  --departure    Departure time (ISO 8601)
-  --arrival      Arrival time (ISO 8601)
+  --from         Origin station/location id, name or @alias (required)
+  --to           Destination station/location id, name or @alias (required)
+  --via          Via station/location id, name or @alias
+  --departure    Departure time (same forms as departures --when)
+  --arrival      Arrival time (same forms as departures --when)
   --results      Maximum number of results
   --transfers    Maximum number of transfers
+  --legs         Show every leg: line, platforms, planned/real times, transfers
+  --later        Page ref for later journeys (laterRef)
+  --earlier      Page ref for earlier journeys (earlierRef)
+  --pages        Number of pages to fetch, following laterRef (default: 1)
+  --show-tokens  Add the refresh_token column (see 'dbrest journey refresh')
+  --products     Only these products, comma-separated: ice, ic/ec, re/ire,
+                 rb, s, u, bus, ferry, str/tram, taxi or API names
+  --exclude-products
+                 Leave out these products, comma-separated
+  --param        Extra query param key=value (repeatable)
+  -h, --help     Show help
+
+NOTE:
+  earlierRef and laterRef of the result are printed to stderr; pass them
+  to --earlier/--later to keep paging. --pages merges the pages and drops
+  duplicate journeys.
+
+EXAMPLE:
+  dbrest journeys --from Berlin --to Hamburg --results 3
+  dbrest journeys --from Berlin --to Hamburg --departure "mon 08:00"
+  dbrest journeys --from 8011160 --to 8002549 --legs
+  dbrest --plain journeys --from 8011160 --to 8002549 --pages 3`)
+}
+
+func printJourneyUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest journey refresh --token <token> [flags]
+  dbrest journey refresh <token> [flags]
+
+FLAGS:
+  --token        Journey refresh token (required)
   --param        Extra query param key=value (repeatable)
   -h, --help     Show help
 
+NOTE:
+  Prints every leg with current real-time data. Get tokens from
+  'dbrest journeys --show-tokens'. --plain columns match 'journeys --legs'.
+
 EXAMPLE:
-  dbrest journeys --from Berlin --to Hamburg --results 3`)
+  dbrest journey refresh '¶HKI¶T$A=1@O=Berlin Hbf@...'`)
 }
 
 func printTripUsage(out io.Writer) {
@@ -855,8 +2159,9 @@ func printTripUsage(out io.Writer) {
   dbrest trip <trip-id> [flags]
 
 FLAGS:
-  --id           Trip id (required)
+  --id           Trip id or @alias (required)
   --line-name    Line name filter
+  --watch        Re-run every interval (e.g. 30s) until Ctrl-C
   --param        Extra query param key=value (repeatable)
   -h, --help     Show help
 
@@ -875,11 +2180,17 @@ FLAGS:
   --east         Eastern longitude (required)
   --results      Maximum number of results
   --duration     Timespan in seconds
+  --watch        Re-run every interval (e.g. 30s) until Ctrl-C
+  --products     Only these products, comma-separated: ice, ic/ec, re/ire,
+                 rb, s, u, bus, ferry, str/tram, taxi or API names
+  --exclude-products
+                 Leave out these products, comma-separated
   --param        Extra query param key=value (repeatable)
   -h, --help     Show help
 
 EXAMPLE:
-  dbrest radar --north 52.6 --south 52.4 --west 13.2 --east 13.5 --results 50`)
+  dbrest radar --north 52.6 --south 52.4 --west 13.2 --east 13.5 --results 50
+  dbrest radar --north 52.6 --south 52.4 --west 13.2 --east 13.5 --products s,u`)
 }
 
 func printRequestUsage(out io.Writer) {
@@ -898,3 +2209,24 @@ NOTE:
 EXAMPLE:
   dbrest request /stations --param query=Berlin --json`)
 }
+
+func printCacheUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest cache stats
+  dbrest cache clear
+
+COMMANDS:
+  stats          Show entry count, expired entries and size
+  clear          Remove all cached responses
+
+NOTE:
+  Responses are cached under $XDG_CACHE_HOME/dbrest. Locations and stops
+  are kept for 24h, departures/arrivals for 30s and radar for 10s; other
+  endpoints are not cached. --cache-ttl overrides the lifetime for all
+  endpoints but journey refresh, and --no-cache bypasses the cache.
+  Expired entries are deleted whenever a new response is stored.
+  --plain prints: dir, entries, expired, bytes.
+
+EXAMPLE:
+  dbrest cache stats`)
+}
@@ -3,21 +3,40 @@ package cli
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/timkrase/deutsche-bahn-skill/internal/api"
 )
 
 type fakeClient struct {
+	mu         sync.Mutex
 	lastPath   string
 	lastParams url.Values
 	response   []byte
+	// responses overrides response for specific paths.
+	responses map[string][]byte
+	// failures makes Get fail for specific paths.
+	failures map[string]error
 }
 
 func (f *fakeClient) Get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.lastPath = path
 	f.lastParams = params
+	if err, ok := f.failures[path]; ok {
+		return nil, err
+	}
+	if body, ok := f.responses[path]; ok {
+		return body, nil
+	}
 	return f.response, nil
 }
 
@@ -25,6 +44,23 @@ func (f *fakeClient) URL(path string, params url.Values) (string, error) {
 	return "http://example.test" + path + "?" + params.Encode(), nil
 }
 
+func runWith(client api.Clienter, args ...string) (int, string, string) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	exit := Run(args, Runner{
+		Out: out,
+		Err: errOut,
+		Getenv: func(string) string {
+			return ""
+		},
+		NewClient: func(cfg api.Config) (api.Clienter, error) {
+			return client, nil
+		},
+		Version: "dev",
+	})
+	return exit, out.String(), errOut.String()
+}
+
 func TestRunLocationsJSON(t *testing.T) {
 	client := &fakeClient{response: []byte(`[]`)}
 	out := &bytes.Buffer{}
@@ -82,3 +118,593 @@ func TestRunMissingCommand(t *testing.T) {
 		t.Fatal("expected usage output on stderr")
 	}
 }
+
+func TestRunDeparturesResolvesStopName(t *testing.T) {
+	client := &fakeClient{
+		response: []byte(`[]`),
+		responses: map[string][]byte{
+			"/locations": []byte(`[{"id":"8011160","name":"Berlin Hbf","type":"stop"}]`),
+		},
+	}
+
+	exit, _, stderr := runWith(client, "--plain", "departures", "Berlin Hbf")
+
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d (%s)", exit, stderr)
+	}
+	if client.lastPath != "/stops/8011160/departures" {
+		t.Fatalf("unexpected path %q", client.lastPath)
+	}
+	if stderr != "using stop 8011160 (Berlin Hbf)\n" {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+}
+
+func TestRunDeparturesStrictAmbiguous(t *testing.T) {
+	client := &fakeClient{
+		responses: map[string][]byte{
+			"/locations": []byte(`[{"id":"1","name":"Berlin Hbf (tief)"},{"id":"2","name":"Berlin Hbf (S-Bahn)"}]`),
+		},
+	}
+
+	exit, _, stderr := runWith(client, "departures", "--strict", "Berlin Hbf")
+
+	if exit != exitError {
+		t.Fatalf("expected exit %d, got %d", exitError, exit)
+	}
+	if client.lastParams.Get("results") != "5" {
+		t.Fatalf("expected strict lookup to request 5 results, got %q", client.lastParams.Get("results"))
+	}
+	if !strings.Contains(stderr, "ambiguous stop") {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+}
+
+type pagedClient struct {
+	fakeClient
+	pages map[string][]byte
+}
+
+func (p *pagedClient) Get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	p.lastPath = path
+	p.lastParams = params
+	return p.pages[params.Get("laterThan")], nil
+}
+
+func TestRunJourneysPages(t *testing.T) {
+	client := &pagedClient{pages: map[string][]byte{
+		"":   []byte(`{"journeys":[{"refreshToken":"a","legs":[{"plannedDeparture":"08:00"}]}],"earlierRef":"e1","laterRef":"l1"}`),
+		"l1": []byte(`{"journeys":[{"refreshToken":"a","legs":[{"plannedDeparture":"08:00"}]},{"refreshToken":"b","legs":[{"plannedDeparture":"09:00"}]}],"earlierRef":"e2","laterRef":"l2"}`),
+	}}
+
+	exit, stdout, stderr := runWith(client, "--plain", "journeys", "--from", "1", "--to", "2", "--pages", "2")
+
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d (%s)", exit, stderr)
+	}
+	if client.lastParams.Get("laterThan") != "l1" {
+		t.Fatalf("expected second page to use laterThan=l1, got %q", client.lastParams.Get("laterThan"))
+	}
+	if stdout != "08:00\t-\t-\t-\t0\n09:00\t-\t-\t-\t0\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+	if stderr != "earlierRef: e1\nlaterRef: l2\n" {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+}
+
+func TestRunJourneyRefresh(t *testing.T) {
+	client := &fakeClient{response: []byte(`{"journey":{"refreshToken":"T$A=1","legs":[{"origin":{"name":"A"},"destination":{"name":"B"},"plannedDeparture":"08:00","departure":"08:05","departureDelay":300,"line":{"name":"RE1"}}]}}`)}
+
+	exit, stdout, stderr := runWith(client, "--plain", "journey", "refresh", "T$A=1")
+
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d (%s)", exit, stderr)
+	}
+	if client.lastPath != "/journeys/T$A=1" {
+		t.Fatalf("unexpected path %q", client.lastPath)
+	}
+	if stdout != "1\tRE1\tA\t-\t08:00\t08:05\t+5m\tB\t-\t-\t-\t-\t-\t-\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+}
+
+func TestRunJourneysShowTokens(t *testing.T) {
+	client := &fakeClient{response: []byte(`{"journeys":[{"refreshToken":"tok","transfers":1,"legs":[{"plannedDeparture":"08:00","plannedArrival":"09:00"}]}]}`)}
+
+	exit, stdout, _ := runWith(client, "--plain", "journeys", "--from", "1", "--to", "2", "--show-tokens")
+
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d", exit)
+	}
+	if stdout != "08:00\t-\t09:00\t-\t1\ttok\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+}
+
+func TestRunConfigShowPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dbrest"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	conf := "[profiles.work]\nbase_url = \"http://profile.test\"\ntimeout = \"20s\"\nresults = 3\n"
+	if err := os.WriteFile(filepath.Join(dir, "dbrest", "config.toml"), []byte(conf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"XDG_CONFIG_HOME": dir, "DBREST_TIMEOUT": "30s"}
+	client := &fakeClient{response: []byte(`[]`)}
+	var gotCfg api.Config
+	out := &bytes.Buffer{}
+	exit := Run([]string{"--plain", "--profile", "work", "config", "show"}, Runner{
+		Out:    out,
+		Err:    &bytes.Buffer{},
+		Getenv: func(key string) string { return env[key] },
+		NewClient: func(cfg api.Config) (api.Clienter, error) {
+			gotCfg = cfg
+			return client, nil
+		},
+	})
+
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d", exit)
+	}
+	if gotCfg.BaseURL != "http://profile.test" || gotCfg.Timeout != 30*time.Second {
+		t.Fatalf("unexpected client config: %+v", gotCfg)
+	}
+	expected := "config\t" + filepath.Join(dir, "dbrest", "config.toml") + "\tdefault\n" +
+		"profile\twork\tflag\n" +
+		"base_url\thttp://profile.test\tprofile work\n" +
+		"timeout\t30s\tenv DBREST_TIMEOUT\n" +
+		"retries\t2\tdefault\n" +
+		"output\tplain\tflag\n" +
+		"results\t3\tprofile work\n" +
+		"products\t-\tdefault\n"
+	if out.String() != expected {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRunBrokenConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dbrest"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dbrest", "config.toml"), []byte("[profiles.work\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"XDG_CONFIG_HOME": dir}
+	run := func(args ...string) (int, string) {
+		errOut := &bytes.Buffer{}
+		exit := Run(args, Runner{
+			Out:    &bytes.Buffer{},
+			Err:    errOut,
+			Getenv: func(key string) string { return env[key] },
+			NewClient: func(cfg api.Config) (api.Clienter, error) {
+				return &fakeClient{response: []byte(`[]`)}, nil
+			},
+		})
+		return exit, errOut.String()
+	}
+
+	for _, args := range [][]string{{"help", "departures"}, {"config", "show"}, {"completion", "bash"}} {
+		exit, stderr := run(args...)
+		if exit != exitOK {
+			t.Fatalf("%v: expected exit 0, got %d (%s)", args, exit, stderr)
+		}
+		if !strings.Contains(stderr, "invalid config (ignored): ") {
+			t.Fatalf("%v: expected a config warning, got %q", args, stderr)
+		}
+	}
+	if exit, stderr := run("locations", "berlin"); exit != exitUsage || !strings.Contains(stderr, "line 1: invalid table header") {
+		t.Fatalf("expected exit 2 with the config error, got %d (%s)", exit, stderr)
+	}
+}
+
+func TestRunUnknownProfile(t *testing.T) {
+	exit, _, stderr := runWith(&fakeClient{}, "--profile", "nope", "locations", "berlin")
+	if exit != exitUsage {
+		t.Fatalf("expected exit %d, got %d", exitUsage, exit)
+	}
+	if !strings.Contains(stderr, `unknown profile "nope"`) {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+}
+
+func TestRunAliasExpansion(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"XDG_CONFIG_HOME": dir}
+	client := &fakeClient{response: []byte(`{"journeys":[]}`)}
+	run := func(args ...string) (int, string) {
+		errOut := &bytes.Buffer{}
+		exit := Run(args, Runner{
+			Out:    &bytes.Buffer{},
+			Err:    errOut,
+			Getenv: func(key string) string { return env[key] },
+			NewClient: func(cfg api.Config) (api.Clienter, error) {
+				return client, nil
+			},
+		})
+		return exit, errOut.String()
+	}
+
+	if exit, stderr := run("alias", "add", "home", "8011160"); exit != exitOK {
+		t.Fatalf("alias add: exit %d: %s", exit, stderr)
+	}
+	if exit, stderr := run("journeys", "--from", "@home", "--to", "8002549"); exit != exitOK {
+		t.Fatalf("journeys: exit %d: %s", exit, stderr)
+	}
+	if got := client.lastParams.Get("from"); got != "8011160" {
+		t.Fatalf("expected @home to expand to 8011160, got %q", got)
+	}
+	exit, stderr := run("departures", "@work")
+	if exit != exitUsage || !strings.Contains(stderr, "unknown alias @work") {
+		t.Fatalf("expected unknown alias error, got %d: %q", exit, stderr)
+	}
+
+	if exit, stderr := run("alias", "add", "office", "900100003", "900003201"); exit != exitOK {
+		t.Fatalf("alias add: exit %d: %s", exit, stderr)
+	}
+	client.response = []byte(`[]`)
+	exit, stderr = run("--verbose", "--no-cache", "departures", "--stop", "@office", "--stop", "8011160")
+	if exit != exitOK {
+		t.Fatalf("departures: exit %d: %s", exit, stderr)
+	}
+	for _, id := range []string{"900100003", "900003201", "8011160"} {
+		if !strings.Contains(stderr, "/stops/"+id+"/departures") {
+			t.Fatalf("expected a request for stop %s, got %q", id, stderr)
+		}
+	}
+	for _, args := range [][]string{{"arrivals", "@office"}, {"journeys", "--from", "@office", "--to", "8002549"}} {
+		if exit, stderr := run(args...); exit != exitUsage || !strings.Contains(stderr, "@office is a group of 2 stops") {
+			t.Fatalf("%v: expected a group alias error, got %d: %q", args, exit, stderr)
+		}
+	}
+
+	if exit, stderr := run("alias", "add", "inv", "Berlin, Invalidenpark"); exit != exitOK {
+		t.Fatalf("alias add: exit %d: %s", exit, stderr)
+	}
+	_, stderr = run("--verbose", "--no-cache", "departures", "@inv")
+	if !strings.Contains(stderr, "query=Berlin%2C+Invalidenpark&") || strings.Count(stderr, "/locations?") != 1 {
+		t.Fatalf("expected one lookup of the whole name, got %q", stderr)
+	}
+}
+
+func TestRunOutputCSV(t *testing.T) {
+	client := &fakeClient{response: []byte(`[{"when":"2024-02-01T12:00:00+01:00","line":{"name":"S1"},"direction":"Wannsee, via \"Zoo\"","platform":"1"}]`)}
+	exit, stdout, stderr := runWith(client, "--output", "csv", "departures", "8011160")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	expected := "time,line,direction,platform,delay,status\n" +
+		"2024-02-01T12:00:00+01:00,S1,\"Wannsee, via \"\"Zoo\"\"\",1,-,-\n"
+	if stdout != expected {
+		t.Fatalf("unexpected stdout:\n%q", stdout)
+	}
+
+	_, stdout, _ = runWith(client, "--output", "tsv", "--no-header", "departures", "8011160")
+	if stdout != "2024-02-01T12:00:00+01:00\tS1\t\"Wannsee, via \"\"Zoo\"\"\"\t1\t-\t-\n" {
+		t.Fatalf("unexpected tsv stdout: %q", stdout)
+	}
+
+	if exit, _, _ := runWith(client, "--plain", "--output", "csv", "departures", "8011160"); exit != exitUsage {
+		t.Fatalf("expected exit %d for --plain with --output, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunOutputNDJSON(t *testing.T) {
+	client := &fakeClient{response: []byte(`{"journeys":[{"transfers":1,"legs":[{"plannedDeparture":"08:00","plannedArrival":"09:00"}]}]}`)}
+
+	exit, stdout, stderr := runWith(client, "--output", "ndjson", "journeys", "--from", "1", "--to", "2")
+
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	expected := `{"departure":"08:00","origin":null,"arrival":"09:00","destination":null,"transfers":1}` + "\n"
+	if stdout != expected {
+		t.Fatalf("unexpected stdout:\n%s", stdout)
+	}
+}
+
+func TestRunFieldsSelectsColumns(t *testing.T) {
+	client := &fakeClient{response: []byte(`[{"tripId":"1|2","when":"12:00","plannedWhen":"11:58","line":{"name":"S1","product":"suburban"},"platform":"1","delay":60}]`)}
+
+	exit, stdout, stderr := runWith(client, "--plain", "--fields", "line,plannedWhen,trip_id,delay", "departures", "8011160")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if stdout != "S1\t11:58\t1|2\t+1m\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+
+	exit, _, stderr = runWith(client, "--fields", "line,nope", "departures", "8011160")
+	if exit != exitUsage || !strings.Contains(stderr, `unknown field "nope"`) {
+		t.Fatalf("expected unknown field error, got %d: %q", exit, stderr)
+	}
+
+	exit, stdout, _ = runWith(client, "help", "fields", "departures")
+	if exit != exitOK || !strings.Contains(stdout, "planned_platform") {
+		t.Fatalf("unexpected help fields output (%d): %s", exit, stdout)
+	}
+}
+
+func TestRunTemplate(t *testing.T) {
+	client := &fakeClient{response: []byte(`[{"when":"2024-02-01T12:00:00+01:00","line":{"name":"S1"},"direction":"Wannsee","delay":120}]`)}
+
+	exit, stdout, stderr := runWith(client, "--template", `{{time "15:04" .When}} {{.Line}} → {{.Direction}} {{delay .Delay}}`, "departures", "8011160")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if stdout != "12:00 S1 → Wannsee +2m\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+
+	exit, stdout, stderr = runWith(client, "--tz", "UTC", "--time-format", "Jan 2 15:04", "--template", `{{time .When}}|{{time "15:04" .When}}`, "departures", "8011160")
+	if exit != exitOK || stdout != "Feb 1 11:00|11:00\n" {
+		t.Fatalf("expected --tz and --time-format to apply to time, got %d: %q (%s)", exit, stdout, stderr)
+	}
+
+	if exit, _, _ := runWith(client, "--template", "{{.Line", "departures", "8011160"); exit != exitUsage {
+		t.Fatalf("expected exit %d for an invalid template, got %d", exitUsage, exit)
+	}
+	if exit, _, _ := runWith(client, "--json", "--template", "{{.Line}}", "departures", "8011160"); exit != exitUsage {
+		t.Fatalf("expected exit %d for --json with --template, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunOutputGeoJSON(t *testing.T) {
+	client := &fakeClient{response: []byte(`{"trip":{"line":{"name":"S1"},"stopovers":[{"stop":{"name":"A","location":{"latitude":52.5,"longitude":13.4}}}]}}`)}
+
+	exit, stdout, stderr := runWith(client, "--output", "geojson", "--fields", "stop", "trip", "1|2")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if client.lastParams.Get("polyline") != "true" {
+		t.Fatalf("expected polyline=true, got %q", client.lastParams.Get("polyline"))
+	}
+	expected := `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[13.4,52.5]},"properties":{"stop":"A"}}]}` + "\n"
+	if stdout != expected {
+		t.Fatalf("unexpected stdout:\n%s", stdout)
+	}
+
+	if exit, _, _ := runWith(client, "--output", "geojson", "departures", "8011160"); exit != exitUsage {
+		t.Fatalf("expected exit %d for geojson departures, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunOutputICS(t *testing.T) {
+	client := &fakeClient{response: []byte(`{"journeys":[{"refreshToken":"tok","legs":[{"origin":{"name":"A"},"destination":{"name":"B"},"plannedDeparture":"2024-01-15T07:00:00Z","plannedArrival":"2024-01-15T08:00:00Z","line":{"name":"RE1"}}]}]}`)}
+
+	exit, stdout, stderr := runWith(client, "--output", "ics", "journeys", "--from", "1", "--to", "2")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if !strings.HasPrefix(stdout, "BEGIN:VCALENDAR\r\n") || !strings.Contains(stdout, "DTSTART;TZID=Europe/Berlin:20240115T080000\r\n") {
+		t.Fatalf("unexpected stdout:\n%s", stdout)
+	}
+
+	if exit, _, stderr := runWith(client, "--output", "ics", "radar", "--north", "1", "--south", "0", "--west", "0", "--east", "1"); exit != exitUsage {
+		t.Fatalf("expected exit %d for ics radar, got %d: %s", exitUsage, exit, stderr)
+	}
+}
+
+func TestRunDeparturesRejectsInvalidWhen(t *testing.T) {
+	client := &fakeClient{response: []byte(`[]`)}
+
+	exit, _, stderr := runWith(client, "departures", "--when", "someday", "8011160")
+	if exit != exitUsage {
+		t.Fatalf("expected exit %d, got %d", exitUsage, exit)
+	}
+	if !strings.Contains(stderr, `invalid --when "someday"`) {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+
+	exit, _, stderr = runWith(client, "--verbose", "--tz", "UTC", "departures", "--when", "2024-02-01T08:00", "8011160")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if client.lastParams.Get("when") != "2024-02-01T08:00:00Z" || !strings.Contains(stderr, "when: 2024-02-01T08:00:00Z") {
+		t.Fatalf("unexpected when %q, stderr %q", client.lastParams.Get("when"), stderr)
+	}
+}
+
+func TestRunTimeFormat(t *testing.T) {
+	client := &fakeClient{response: []byte(`[{"when":"2024-02-01T12:00:00+01:00","line":{"name":"S1"}}]`)}
+
+	exit, stdout, stderr := runWith(client, "--plain", "--time-format", "hh:mm", "--tz", "UTC", "--fields", "time,line", "departures", "8011160")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if stdout != "11:00\tS1\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+
+	_, stdout, _ = runWith(client, "--plain", "--fields", "time", "departures", "8011160")
+	if stdout != "2024-02-01T12:00:00+01:00\n" {
+		t.Fatalf("expected --plain to keep ISO times, got %q", stdout)
+	}
+
+	exit, stdout, stderr = runWith(client, "--plain", "--time-format", "Jan 2 15:04", "--tz", "UTC", "--fields", "time", "departures", "8011160")
+	if exit != exitOK || stdout != "Feb 1 11:00\n" {
+		t.Fatalf("expected a Go layout to be accepted, got %d: %q (%s)", exit, stdout, stderr)
+	}
+
+	if exit, _, _ := runWith(client, "--time-format", "short", "departures", "8011160"); exit != exitUsage {
+		t.Fatalf("expected exit %d for an invalid --time-format, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunProductFlags(t *testing.T) {
+	client := &fakeClient{response: []byte(`[]`)}
+
+	exit, _, stderr := runWith(client, "--plain", "departures", "--products", "ice,IC,s", "--param", "regional=true", "8011160")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	want := map[string]string{"nationalExpress": "true", "national": "true", "suburban": "true", "regional": "true", "bus": "false", "taxi": "false"}
+	for key, value := range want {
+		if got := client.lastParams.Get(key); got != value {
+			t.Fatalf("expected %s=%s, got %q", key, value, got)
+		}
+	}
+
+	client.response = []byte(`{"journeys":[]}`)
+	exit, _, _ = runWith(client, "--plain", "journeys", "--from", "1", "--to", "2", "--exclude-products", "bus")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d", exit)
+	}
+	if client.lastParams.Get("bus") != "false" || client.lastParams.Has("national") {
+		t.Fatalf("unexpected product params: %v", client.lastParams)
+	}
+
+	exit, _, stderr = runWith(client, "radar", "--north", "1", "--south", "0", "--west", "0", "--east", "1", "--products", "zeppelin")
+	if exit != exitUsage || !strings.Contains(stderr, `unknown product "zeppelin"`) {
+		t.Fatalf("expected exit %d for an unknown product, got %d: %s", exitUsage, exit, stderr)
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	client := &fakeClient{response: []byte(`{"type":"stop","id":"8011160","name":"Berlin Hbf","location":{"latitude":52.525,"longitude":13.369},"lines":[{"name":"S5"}]}`)}
+
+	exit, stdout, stderr := runWith(client, "--plain", "stop", "8011160")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if client.lastPath != "/stops/8011160" || client.lastParams.Get("linesOfStops") != "true" {
+		t.Fatalf("unexpected request %s %v", client.lastPath, client.lastParams)
+	}
+	if stdout != "8011160\tBerlin Hbf\tstop\t52.525000\t13.369000\t-\tS5\t-\t-\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+}
+
+func TestRunDeparturesMergesStops(t *testing.T) {
+	client := &fakeClient{
+		responses: map[string][]byte{
+			"/stops/1/departures": []byte(`[{"tripId":"a","when":"2024-01-01T12:05:00+01:00","line":{"name":"M4"},"direction":"Zoo","stop":{"id":"1","name":"Tram"}},` +
+				`{"tripId":"b","when":"2024-01-01T12:09:00+01:00","line":{"name":"200"},"direction":"Zoo","stop":{"id":"1","name":"Tram"}}]`),
+			"/stops/2/departures": []byte(`{"departures":[{"tripId":"b","when":"2024-01-01T12:07:00+01:00","line":{"name":"200"},"direction":"Zoo","stop":{"id":"2","name":"Bus"}}]}`),
+		},
+		failures: map[string]error{"/stops/3/departures": errors.New("503 Service Unavailable")},
+	}
+
+	exit, stdout, stderr := runWith(client, "--plain", "--fields", "line,stop", "departures", "--stop", "1", "--stop", "2")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if stdout != "M4\tTram\n200\tBus\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+
+	exit, stdout, stderr = runWith(client, "--plain", "--fields", "line", "departures", "--results", "1", "--stop", "1", "--stop", "2")
+	if exit != exitOK || stdout != "M4\n" {
+		t.Fatalf("expected --results to limit the merged board, got %d: %q (%s)", exit, stdout, stderr)
+	}
+	if got := client.lastParams.Get("results"); got != "1" {
+		t.Fatalf("expected results=1 per stop, got %q", got)
+	}
+
+	exit, stdout, stderr = runWith(client, "--plain", "--fields", "line", "departures", "--stop", "1", "--stop", "3")
+	if exit != exitError {
+		t.Fatalf("expected exit %d, got %d", exitError, exit)
+	}
+	if stdout != "M4\n200\n" || stderr != "stop 3: 503 Service Unavailable\n" {
+		t.Fatalf("unexpected output %q, stderr %q", stdout, stderr)
+	}
+
+	if exit, _, _ := runWith(client, "departures", "--stop", "1", "--stop", "2", "--watch", "30s"); exit != exitUsage {
+		t.Fatalf("expected exit %d for --watch with several stops, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunDeparturesFollow(t *testing.T) {
+	client := &fakeClient{responses: map[string][]byte{
+		"/stops/2/departures": []byte(`[{"tripId":"x","line":{"name":"S1"},"direction":"Oranienburg","stop":{"id":"2"}},` +
+			`{"tripId":"1|2","line":{"name":"S7"},"direction":"Ahrensfelde","stop":{"id":"2"}}]`),
+		"/trips/1%7C2": []byte(`{"trip":{"line":{"name":"S7"},"stopovers":[` +
+			`{"stop":{"id":"1","name":"Potsdam"},"departure":"2024-01-01T12:00:00+01:00"},` +
+			`{"stop":{"id":"2","name":"Zoo"},"arrival":"2024-01-01T12:30:00+01:00","arrivalDelay":180},` +
+			`{"stop":{"id":"3","name":"Ahrensfelde"},"arrival":"2024-01-01T13:10:00+01:00","arrivalDelay":60}]}}`),
+	}}
+
+	exit, stdout, stderr := runWith(client, "--plain", "--fields", "stop,arrival_delay", "departures", "--follow", "2", "2")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if client.lastPath != "/trips/1%7C2" {
+		t.Fatalf("unexpected path %s", client.lastPath)
+	}
+	if stdout != "Zoo\t+3m\nAhrensfelde\t+1m\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+	if stderr != "following S7 to Ahrensfelde (1|2)\n" {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+
+	exit, _, stderr = runWith(client, "departures", "--follow", "3", "2")
+	if exit != exitError || !strings.Contains(stderr, "only 2 departures") {
+		t.Fatalf("expected exit %d for a missing row, got %d: %q", exitError, exit, stderr)
+	}
+	if exit, _, _ := runWith(client, "departures", "--follow", "1", "--watch", "30s", "2"); exit != exitUsage {
+		t.Fatalf("expected exit %d with --watch, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunReachable(t *testing.T) {
+	client := &fakeClient{response: []byte(`[{"duration":5,"stations":[{"id":"1","name":"A"}]},{"duration":18,"stations":[{"id":"2","name":"B"},{"id":"3","name":"C"}]}]`)}
+
+	exit, stdout, stderr := runWith(client, "--plain", "--fields", "duration,id", "reachable", "--lat", "52.5", "--lon", "13.4", "--max-duration", "30", "--max-transfers", "1")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if client.lastPath != "/stops/reachable-from" {
+		t.Fatalf("unexpected path %s", client.lastPath)
+	}
+	params := client.lastParams
+	if params.Get("maxDuration") != "30" || params.Get("maxTransfers") != "1" || params.Get("address") != "52.500000,13.400000" || params.Get("longitude") != "13.400000" {
+		t.Fatalf("unexpected params %v", params)
+	}
+	if stdout != "5\t1\n18\t2\n18\t3\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+
+	exit, stdout, stderr = runWith(client, "reachable", "--lat", "52.5", "--lon", "13.4")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if client.lastParams.Has("maxTransfers") {
+		t.Fatalf("maxTransfers should be unset, got %v", client.lastParams)
+	}
+	first, second := strings.Index(stdout, "up to 10 min:"), strings.Index(stdout, "10-20 min:")
+	if first < 0 || second < first {
+		t.Fatalf("unexpected grouped output:\n%s", stdout)
+	}
+
+	if exit, _, _ := runWith(client, "reachable", "--max-duration", "30"); exit != exitUsage {
+		t.Fatalf("expected exit %d without a start, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunNearbyGeocodes(t *testing.T) {
+	client := &fakeClient{responses: map[string][]byte{
+		"/locations":        []byte(`[{"type":"location","name":"Alexanderplatz","latitude":52.5219,"longitude":13.4132}]`),
+		"/locations/nearby": []byte(`[{"id":"2","name":"B","distance":300},{"id":"1","name":"A","distance":120}]`),
+	}}
+
+	exit, stdout, stderr := runWith(client, "--plain", "--fields", "id,distance_m", "nearby", "--near", "Alexanderplatz", "--distance", "500")
+	if exit != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", exit, stderr)
+	}
+	if client.lastPath != "/locations/nearby" || client.lastParams.Get("latitude") != "52.521900" || client.lastParams.Get("distance") != "500" {
+		t.Fatalf("unexpected request %s %v", client.lastPath, client.lastParams)
+	}
+	if stdout != "1\t120\n2\t300\n" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+	if stderr != "using location Alexanderplatz (52.521900, 13.413200)\n" {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+
+	if exit, _, _ := runWith(client, "nearby", "--lat", "52.5"); exit != exitUsage {
+		t.Fatalf("expected exit %d without --lon, got %d", exitUsage, exit)
+	}
+}
@@ -0,0 +1,588 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+// completionCommand describes one subcommand for the completion scripts.
+// Flags are read from the command's own flag set, see describe.
+type completionCommand struct {
+	name    string
+	summary string
+	// run and args reach the command's flag set; run is nil for commands
+	// without flags.
+	run  func(args []string, sess *session) int
+	args []string
+	// subcommands complete the first argument.
+	subcommands []string
+	// values is the __complete kind for the remaining arguments.
+	values string
+}
+
+// completionFlag is one flag of a command or of the global flag set.
+type completionFlag struct {
+	name       string
+	usage      string
+	takesValue bool
+	repeatable bool
+	// values is the __complete kind for the flag's value, if any.
+	values string
+}
+
+type completionSpec struct {
+	global   []completionFlag
+	commands []completionCommand
+	flags    map[string][]completionFlag
+}
+
+var completionCommands = []completionCommand{
+	{name: "locations", summary: "Search for stations/places/addresses", run: runLocations},
+	{name: "nearby", summary: "List stops near coordinates or an address", run: runNearby},
+	{name: "reachable", summary: "List stops reachable within a travel time", run: runReachable},
+	{name: "stop", summary: "Show details of a stop", run: runStop, values: "stops"},
+	{name: "departures", summary: "List departures for a stop", run: runDepartures, values: "stops"},
+	{name: "arrivals", summary: "List arrivals for a stop", run: runArrivals, values: "stops"},
+	{name: "journeys", summary: "Find journeys between two locations", run: runJourneys},
+	{name: "journey", summary: "Refresh a journey by its refresh token", run: runJourney, args: []string{"refresh"}, subcommands: []string{"refresh"}},
+	{name: "trip", summary: "Fetch a trip by id", run: runTrip},
+	{name: "radar", summary: "List vehicle movements in a bounding box", run: runRadar},
+	{name: "request", summary: "Perform a raw GET request", run: runRequest},
+	{name: "cache", summary: "Show or clear the response cache", subcommands: []string{"stats", "clear"}},
+	{name: "config", summary: "Show the effective configuration", run: runConfig, subcommands: []string{"show"}},
+	{name: "alias", summary: "Save stop ids as @aliases", subcommands: []string{"add", "list", "rm"}, values: "aliases"},
+	{name: "completion", summary: "Print a shell completion script", subcommands: []string{"bash", "zsh", "fish"}},
+	{name: "help", summary: "Show command help"},
+}
+
+// describe hands fs to the completion generator when the session only
+// collects flag sets. It reports whether the command should return
+// without parsing or running anything.
+func (s *session) describe(fs *flag.FlagSet) bool {
+	if s.collect == nil {
+		return false
+	}
+	s.collect(fs)
+	return true
+}
+
+func describeCommands(global *flag.FlagSet) completionSpec {
+	spec := completionSpec{
+		global:   completionFlags(global),
+		commands: completionCommands,
+		flags:    map[string][]completionFlag{},
+	}
+	for _, cmd := range completionCommands {
+		if cmd.run == nil {
+			continue
+		}
+		probe := &session{out: io.Discard, errOut: io.Discard}
+		probe.collect = func(fs *flag.FlagSet) {
+			spec.flags[cmd.name] = completionFlags(fs)
+		}
+		cmd.run(cmd.args, probe)
+	}
+	return spec
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{name: f.Name, usage: f.Usage, takesValue: true}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			cf.takesValue = false
+		}
+		switch f.Value.(type) {
+		case *paramList, *stopList:
+			cf.repeatable = true
+		}
+		switch f.Name {
+		case "stop", "from", "to", "via":
+			cf.values = "stops"
+		case "profile":
+			cf.values = "profiles"
+		case "output":
+			cf.values = "outputs"
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+func (f completionFlag) option() string {
+	if len(f.name) == 1 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+func (s completionSpec) commandNames() []string {
+	names := make([]string, 0, len(s.commands))
+	for _, cmd := range s.commands {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+func runCompletion(args []string, sess *session, global *flag.FlagSet) int {
+	if len(args) == 0 {
+		printCompletionUsage(sess.errOut)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "--help" {
+		printCompletionUsage(sess.out)
+		return exitOK
+	}
+	if len(args) > 1 {
+		_, _ = fmt.Fprintf(sess.errOut, "unexpected argument: %s\n", args[1])
+		printCompletionUsage(sess.errOut)
+		return exitUsage
+	}
+	spec := describeCommands(global)
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(sess.out, spec)
+	case "zsh":
+		writeZshCompletion(sess.out, spec)
+	case "fish":
+		writeFishCompletion(sess.out, spec)
+	default:
+		_, _ = fmt.Fprintf(sess.errOut, "unknown shell: %s (expected bash, zsh or fish)\n", args[0])
+		return exitUsage
+	}
+	return exitOK
+}
+
+// runComplete backs the completion scripts: it prints candidates of one
+// kind (stops, aliases, profiles or outputs) matching a prefix, one per line.
+// Request failures print nothing so a slow or offline API never shows up
+// in the shell.
+func runComplete(args []string, sess *session) int {
+	if len(args) == 0 || len(args) > 2 {
+		return exitUsage
+	}
+	prefix := ""
+	if len(args) == 2 {
+		prefix = args[1]
+	}
+	var candidates []string
+	switch args[0] {
+	case "stops":
+		for _, name := range sess.conf.AliasNames() {
+			if strings.HasPrefix("@"+name, prefix) {
+				candidates = append(candidates, "@"+name)
+			}
+		}
+		if !strings.HasPrefix(prefix, "@") && len(strings.TrimSpace(prefix)) >= 2 {
+			candidates = append(candidates, stopNames(sess, prefix)...)
+		}
+	case "aliases":
+		for _, name := range sess.conf.AliasNames() {
+			if strings.HasPrefix(name, strings.TrimPrefix(prefix, "@")) {
+				candidates = append(candidates, name)
+			}
+		}
+	case "profiles":
+		for _, name := range sess.conf.ProfileNames() {
+			if strings.HasPrefix(name, prefix) {
+				candidates = append(candidates, name)
+			}
+		}
+	case "outputs":
+		for _, name := range outputModeNames {
+			if strings.HasPrefix(name, prefix) {
+				candidates = append(candidates, name)
+			}
+		}
+	default:
+		return exitUsage
+	}
+	for _, candidate := range candidates {
+		_, _ = fmt.Fprintln(sess.out, candidate)
+	}
+	return exitOK
+}
+
+func stopNames(sess *session, query string) []string {
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("results", "10")
+	values.Set("stops", "true")
+	values.Set("addresses", "false")
+	values.Set("poi", "false")
+	data, err := sess.client.Get(sess.ctx, "/locations", values)
+	if err != nil {
+		return nil
+	}
+	var locations []format.Location
+	if err := json.Unmarshal(data, &locations); err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, loc := range locations {
+		if loc.Name == "" || seen[loc.Name] {
+			continue
+		}
+		seen[loc.Name] = true
+		names = append(names, loc.Name)
+	}
+	return names
+}
+
+func writeBashCompletion(out io.Writer, spec completionSpec) {
+	var b strings.Builder
+	valueFlags := map[string]bool{}
+	var valueOrder []string
+	addValueFlags := func(flags []completionFlag) {
+		for _, f := range flags {
+			if f.takesValue && !valueFlags[f.option()] {
+				valueFlags[f.option()] = true
+				valueOrder = append(valueOrder, f.option())
+			}
+		}
+	}
+	addValueFlags(spec.global)
+	for _, cmd := range spec.commands {
+		addValueFlags(spec.flags[cmd.name])
+	}
+	var plainValues []string
+	for _, option := range valueOrder {
+		switch option {
+		case "--stop", "--from", "--to", "--via", "--profile", "--output":
+		default:
+			plainValues = append(plainValues, option)
+		}
+	}
+
+	b.WriteString(`# bash completion for dbrest
+# Load with: source <(dbrest completion bash)
+
+_dbrest_values() {
+    local IFS=$'\n'
+    COMPREPLY=($(dbrest __complete "$1" "$cur" 2>/dev/null))
+    COMPREPLY=("${COMPREPLY[@]// /\\ }")
+    COMPREPLY=("${COMPREPLY[@]/#/$value_prefix}")
+}
+
+# _dbrest_words sets words and cword like COMP_WORDS and COMP_CWORD, but
+# rejoins what bash splits at the characters of COMP_WORDBREAKS (@, =, :),
+# so that "--stop=@ho" stays one word.
+_dbrest_words() {
+    local line="$COMP_LINE" i piece
+    words=()
+    cword=0
+    for ((i = 0; i < ${#COMP_WORDS[@]}; i++)); do
+        piece="${COMP_WORDS[i]}"
+        if [[ ${#words[@]} -eq 0 || "$line" == [[:space:]]* ]]; then
+            line="${line#"${line%%[![:space:]]*}"}"
+            words+=("$piece")
+        else
+            words[${#words[@]}-1]+="$piece"
+        fi
+        line="${line#"$piece"}"
+        if ((i == COMP_CWORD)); then
+            cword=$((${#words[@]} - 1))
+        fi
+    done
+}
+
+_dbrest() {
+    local cur prev words cword strip value_prefix i
+    _dbrest_words
+    cur="${words[cword]}"
+    prev=""
+    if ((cword > 0)); then
+        prev="${words[cword-1]}"
+    fi
+    # bash replaces only its own last piece of the word, so replies lose
+    # whatever comes before it.
+    strip="${cur%"${COMP_WORDS[COMP_CWORD]}"}"
+    value_prefix=""
+    if [[ "$cur" == --*=* ]]; then
+        prev="${cur%%=*}"
+        value_prefix="$prev="
+        cur="${cur#*=}"
+    fi
+    COMPREPLY=()
+    _dbrest_reply
+    if [[ -n "$strip" ]]; then
+        for i in "${!COMPREPLY[@]}"; do
+            COMPREPLY[i]="${COMPREPLY[i]#"$strip"}"
+        done
+    fi
+}
+
+_dbrest_reply() {
+    local word cmd sub i
+    cmd=""
+    sub=""
+    for ((i = 1; i < cword; i++)); do
+        word="${words[i]}"
+        case "$word" in
+        -*) continue ;;
+        esac
+        case "${words[i-1]}" in
+        `)
+	b.WriteString(strings.Join(valueOrder, "|"))
+	b.WriteString(`) continue ;;
+        esac
+        if [[ -z "$cmd" ]]; then
+            cmd="$word"
+        elif [[ -z "$sub" ]]; then
+            sub="$word"
+        fi
+    done
+
+    case "$prev" in
+    --stop|--from|--to|--via)
+        _dbrest_values stops
+        return
+        ;;
+    --profile)
+        _dbrest_values profiles
+        return
+        ;;
+    --output)
+        _dbrest_values outputs
+        return
+        ;;
+    `)
+	b.WriteString(strings.Join(plainValues, "|"))
+	b.WriteString(`)
+        return
+        ;;
+    esac
+    if [[ -n "$value_prefix" ]]; then
+        return
+    fi
+
+    if [[ -z "$cmd" ]]; then
+        if [[ "$cur" == -* ]]; then
+            COMPREPLY=($(compgen -W "`)
+	b.WriteString(bashOptions(spec.global))
+	b.WriteString(`" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "`)
+	b.WriteString(strings.Join(spec.commandNames(), " "))
+	b.WriteString(`" -- "$cur"))
+        fi
+        return
+    fi
+
+    case "$cmd" in
+`)
+	for _, cmd := range spec.commands {
+		fmt.Fprintf(&b, "    %s)\n", cmd.name)
+		if flags := spec.flags[cmd.name]; len(flags) > 0 {
+			fmt.Fprintf(&b, "        if [[ \"$cur\" == -* ]]; then\n            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n            return\n        fi\n", bashOptions(flags))
+		}
+		words := cmd.subcommands
+		if cmd.name == "help" {
+			words = append(spec.commandNames(), "fields")
+		}
+		switch {
+		case len(words) > 0 && cmd.values != "":
+			fmt.Fprintf(&b, "        if [[ -z \"$sub\" ]]; then\n            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n        else\n            _dbrest_values %s\n        fi\n", strings.Join(words, " "), cmd.values)
+		case len(words) > 0:
+			fmt.Fprintf(&b, "        if [[ -z \"$sub\" ]]; then\n            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n        fi\n", strings.Join(words, " "))
+		case cmd.values != "":
+			fmt.Fprintf(&b, "        _dbrest_values %s\n", cmd.values)
+		}
+		b.WriteString("        ;;\n")
+	}
+	b.WriteString(`    esac
+}
+
+complete -F _dbrest dbrest
+`)
+	_, _ = io.WriteString(out, b.String())
+}
+
+func bashOptions(flags []completionFlag) string {
+	options := make([]string, 0, len(flags))
+	for _, f := range flags {
+		options = append(options, f.option())
+	}
+	return strings.Join(options, " ")
+}
+
+func writeZshCompletion(out io.Writer, spec completionSpec) {
+	var b strings.Builder
+	b.WriteString(`#compdef dbrest
+# zsh completion for dbrest
+# Load with: source <(dbrest completion zsh)
+
+_dbrest_values() {
+  local -a items
+  items=(${(f)"$(dbrest __complete $1 "$PREFIX" 2>/dev/null)"})
+  (( ${#items} )) && compadd -U -- "${items[@]}"
+}
+
+_dbrest() {
+  local curcontext="$curcontext" state line
+  typeset -A opt_args
+
+  _arguments -C \
+`)
+	for _, f := range spec.global {
+		fmt.Fprintf(&b, "    %s \\\n", zshFlagSpec(f))
+	}
+	b.WriteString(`    '1:command:->command' \
+    '*::arg:->args'
+
+  case $state in
+  command)
+    local -a commands
+    commands=(
+`)
+	for _, cmd := range spec.commands {
+		fmt.Fprintf(&b, "      %s\n", zshQuote(zshEscape(cmd.name)+":"+cmd.summary))
+	}
+	b.WriteString(`    )
+    _describe -t commands command commands
+    ;;
+  args)
+    case $line[1] in
+`)
+	for _, cmd := range spec.commands {
+		var specs []string
+		for _, f := range spec.flags[cmd.name] {
+			specs = append(specs, zshFlagSpec(f))
+		}
+		words := cmd.subcommands
+		if cmd.name == "help" {
+			words = append(spec.commandNames(), "fields")
+		}
+		switch {
+		case len(words) > 0:
+			specs = append(specs, zshQuote("1:command:("+strings.Join(words, " ")+")"))
+			if cmd.values != "" {
+				specs = append(specs, zshQuote("*:value:{_dbrest_values "+cmd.values+"}"))
+			}
+		case cmd.values != "":
+			specs = append(specs, zshQuote("1:value:{_dbrest_values "+cmd.values+"}"))
+		}
+		if len(specs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "    %s)\n      _arguments \\\n        %s\n      ;;\n", cmd.name, strings.Join(specs, " \\\n        "))
+	}
+	b.WriteString(`    esac
+    ;;
+  esac
+}
+
+if [ "$funcstack[1]" = "_dbrest" ]; then
+  _dbrest "$@"
+else
+  compdef _dbrest dbrest
+fi
+`)
+	_, _ = io.WriteString(out, b.String())
+}
+
+func zshFlagSpec(f completionFlag) string {
+	spec := f.option()
+	if f.repeatable {
+		spec = "*" + spec
+	}
+	if !f.takesValue {
+		return zshQuote(spec + "[" + zshEscape(f.usage) + "]")
+	}
+	action := " "
+	if f.values != "" {
+		action = "{_dbrest_values " + f.values + "}"
+	}
+	return zshQuote(spec + "=[" + zshEscape(f.usage) + "]:" + f.name + ":" + action)
+}
+
+// zshEscape protects the characters _arguments treats specially in
+// descriptions.
+func zshEscape(s string) string {
+	return strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(s)
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeFishCompletion(out io.Writer, spec completionSpec) {
+	var b strings.Builder
+	b.WriteString(`# fish completion for dbrest
+# Load with: dbrest completion fish | source
+
+function __dbrest_values
+    dbrest __complete $argv[1] (commandline -ct) 2>/dev/null
+end
+
+complete -c dbrest -f
+`)
+	for _, f := range spec.global {
+		b.WriteString(fishFlagLine("__fish_use_subcommand", f))
+	}
+	for _, cmd := range spec.commands {
+		fmt.Fprintf(&b, "complete -c dbrest -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+	for _, cmd := range spec.commands {
+		cond := "__fish_seen_subcommand_from " + cmd.name
+		for _, f := range spec.flags[cmd.name] {
+			b.WriteString(fishFlagLine(cond, f))
+		}
+		words := cmd.subcommands
+		if cmd.name == "help" {
+			words = append(spec.commandNames(), "fields")
+		}
+		if len(words) > 0 {
+			fmt.Fprintf(&b, "complete -c dbrest -n %s -a %s\n",
+				fishQuote(cond+"; and not __fish_seen_subcommand_from "+strings.Join(words, " ")), fishQuote(strings.Join(words, " ")))
+			if cmd.values != "" {
+				fmt.Fprintf(&b, "complete -c dbrest -n %s -a %s\n",
+					fishQuote(cond+"; and __fish_seen_subcommand_from "+strings.Join(words, " ")), fishQuote("(__dbrest_values "+cmd.values+")"))
+			}
+		} else if cmd.values != "" {
+			fmt.Fprintf(&b, "complete -c dbrest -n %s -a %s\n", fishQuote(cond), fishQuote("(__dbrest_values "+cmd.values+")"))
+		}
+	}
+	_, _ = io.WriteString(out, b.String())
+}
+
+func fishFlagLine(cond string, f completionFlag) string {
+	option := "-l " + f.name
+	if len(f.name) == 1 {
+		option = "-s " + f.name
+	}
+	line := fmt.Sprintf("complete -c dbrest -n %s %s", fishQuote(cond), option)
+	if f.takesValue {
+		line += " -x"
+		if f.values != "" {
+			line += " -a " + fishQuote("(__dbrest_values "+f.values+")")
+		}
+	}
+	return line + " -d " + fishQuote(f.usage) + "\n"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func printCompletionUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest completion bash|zsh|fish
+
+NOTE:
+  Prints a completion script for subcommands and flags. Stop flags
+  (--stop, --from, --to, --via) and stop arguments complete @aliases and
+  station names looked up through /locations.
+
+EXAMPLE:
+  source <(dbrest completion bash)
+  source <(dbrest completion zsh)
+  dbrest completion fish | source`)
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/api"
+)
+
+func TestRunCompletionUsesFlagSets(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		exit, stdout, stderr := runWith(&fakeClient{}, "completion", shell)
+		if exit != exitOK {
+			t.Fatalf("%s: expected exit 0, got %d: %s", shell, exit, stderr)
+		}
+		for _, want := range []string{"departures", "show-tokens", "line-name", "__complete"} {
+			if !strings.Contains(stdout, want) {
+				t.Fatalf("%s: script does not mention %q", shell, want)
+			}
+		}
+	}
+	_, stdout, _ := runWith(&fakeClient{}, "completion", "zsh")
+	if !strings.Contains(stdout, `'*--stop=[`) {
+		t.Fatal("zsh: expected the repeatable --stop of departures to be marked with *")
+	}
+	if exit, _, _ := runWith(&fakeClient{}, "completion", "tcsh"); exit != exitUsage {
+		t.Fatalf("expected exit %d for unknown shell, got %d", exitUsage, exit)
+	}
+}
+
+func TestRunCompleteStops(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dbrest"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dbrest", "config.toml"), []byte("[aliases]\nbhf = \"8011160\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeClient{response: []byte(`[{"type":"stop","id":"8011160","name":"Berlin Hbf"},{"type":"stop","id":"8089021","name":"Berlin Hbf (S)"},{"type":"stop","id":"8011160","name":"Berlin Hbf"}]`)}
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		Run(args, Runner{
+			Out:    out,
+			Err:    &bytes.Buffer{},
+			Getenv: func(key string) string { return map[string]string{"XDG_CONFIG_HOME": dir}[key] },
+			NewClient: func(cfg api.Config) (api.Clienter, error) {
+				return client, nil
+			},
+		})
+		return out.String()
+	}
+
+	if got := run("__complete", "stops", "@"); got != "@bhf\n" {
+		t.Fatalf("unexpected alias candidates: %q", got)
+	}
+	if got := run("__complete", "stops", "berl"); got != "Berlin Hbf\nBerlin Hbf (S)\n" {
+		t.Fatalf("unexpected stop candidates: %q", got)
+	}
+	if client.lastPath != "/locations" || client.lastParams.Get("query") != "berl" {
+		t.Fatalf("unexpected request: %s %v", client.lastPath, client.lastParams)
+	}
+}
+
+func TestBashCompletionRejoinsWordBreaks(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	_, script, _ := runWith(&fakeClient{}, "completion", "bash")
+	// Each case is COMP_LINE, COMP_CWORD and COMP_WORDS as bash splits them
+	// with its default COMP_WORDBREAKS; the stub stands in for dbrest.
+	script += `
+dbrest() { [[ "$3" == @* ]] && echo @home || echo "Berlin Hbf"; }
+t() { COMP_LINE="$1"; COMP_CWORD=$2; shift 2; COMP_WORDS=("$@"); _dbrest; echo "${COMPREPLY[*]}"; }
+t 'dbrest departures --stop @ho' 4 dbrest departures --stop @ ho
+t 'dbrest departures --stop=@ho' 5 dbrest departures --stop = @ ho
+t 'dbrest departures --stop=Ber' 4 dbrest departures --stop = Ber
+t 'dbrest departures --stop Ber' 3 dbrest departures --stop Ber
+`
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash: %v: %s", err, out)
+	}
+	expected := "home\nhome\nBerlin\\ Hbf\nBerlin\\ Hbf\n"
+	if string(out) != expected {
+		t.Fatalf("unexpected replies:\n%s", out)
+	}
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+// commandFields maps commands to the fields their tables offer for
+// --fields. Commands with several tables appear once per table.
+var commandFields = []struct {
+	command string
+	fields  func() []format.Column
+}{
+	{"locations", format.LocationFields},
+	{"nearby", format.LocationFields},
+	{"reachable", format.ReachableFields},
+	{"stop", format.StopFields},
+	{"departures", format.StopoverFields},
+	{"arrivals", format.StopoverFields},
+	{"journeys", format.JourneyFields},
+	{"journeys --legs", format.LegFields},
+	{"journey refresh", format.LegFields},
+	{"trip", format.TripFields},
+	{"radar", format.RadarFields},
+}
+
+func runHelpFields(args []string, out io.Writer, errOut io.Writer) int {
+	if len(args) != 1 {
+		printFieldsUsage(errOut)
+		return exitUsage
+	}
+	found := false
+	for _, entry := range commandFields {
+		if entry.command != args[0] && !strings.HasPrefix(entry.command, args[0]+" ") {
+			continue
+		}
+		if found {
+			_, _ = fmt.Fprintln(out)
+		}
+		found = true
+		var defaults, extras strings.Builder
+		for _, col := range entry.fields() {
+			line := fmt.Sprintf("  %-28s %s\n", col.Name, format.FieldUsage(col.Name))
+			if col.Extra {
+				extras.WriteString(line)
+			} else {
+				defaults.WriteString(line)
+			}
+		}
+		_, _ = fmt.Fprintf(out, "FIELDS (%s):\n%s\nEXTRA FIELDS (%s):\n%s", entry.command, defaults.String(), entry.command, extras.String())
+	}
+	if !found {
+		_, _ = fmt.Fprintf(errOut, "no fields for command: %s\n", args[0])
+		printFieldsUsage(errOut)
+		return exitUsage
+	}
+	return exitOK
+}
+
+func printFieldsUsage(out io.Writer) {
+	commands := make([]string, 0, len(commandFields))
+	for _, entry := range commandFields {
+		if name, _, _ := strings.Cut(entry.command, " "); len(commands) == 0 || commands[len(commands)-1] != name {
+			commands = append(commands, name)
+		}
+	}
+	_, _ = fmt.Fprintf(out, `USAGE:
+  dbrest help fields <command>
+
+COMMANDS:
+  %s
+
+NOTE:
+  FIELDS are printed by default, in that order. --fields a,b,c prints
+  the named fields instead, in the given order, and may include EXTRA
+  FIELDS. Names are matched ignoring case and underscores, so plannedWhen
+  selects planned_when. --fields applies to all outputs except --json.
+
+EXAMPLE:
+  dbrest --fields line,time,platform,delay departures 8011160
+`, strings.Join(commands, " "))
+}
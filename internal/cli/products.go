@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// apiProducts are the product toggles of the v6 API, in API order.
+var apiProducts = []string{
+	"nationalExpress",
+	"national",
+	"regionalExpress",
+	"regional",
+	"suburban",
+	"bus",
+	"ferry",
+	"subway",
+	"tram",
+	"taxi",
+}
+
+// productAliases are the short names accepted besides the API names.
+var productAliases = []struct{ alias, product string }{
+	{"ice", "nationalExpress"},
+	{"ic", "national"},
+	{"ec", "national"},
+	{"re", "regionalExpress"},
+	{"ire", "regionalExpress"},
+	{"rb", "regional"},
+	{"s", "suburban"},
+	{"sbahn", "suburban"},
+	{"u", "subway"},
+	{"ubahn", "subway"},
+	{"str", "tram"},
+}
+
+// productFilter holds a command's --products and --exclude-products flags.
+type productFilter struct {
+	include string
+	exclude string
+}
+
+func addProductFlags(fs *flag.FlagSet) *productFilter {
+	p := &productFilter{}
+	fs.StringVar(&p.include, "products", "", "Only these products, comma-separated (e.g. ice,ic,re,s)")
+	fs.StringVar(&p.exclude, "exclude-products", "", "Leave out these products, comma-separated (e.g. bus)")
+	return p
+}
+
+// apply sets the product toggles. --products replaces the profile's list
+// and --exclude-products switches products off; toggles given with
+// --param are left alone. Unknown names are reported to stderr.
+func (p *productFilter) apply(sess *session, values url.Values) bool {
+	include := sess.profile.Products
+	if p.include != "" {
+		parsed, err := parseProducts(splitList(p.include))
+		if err != nil {
+			_, _ = fmt.Fprintf(sess.errOut, "invalid --products: %v\n", err)
+			return false
+		}
+		include = parsed
+	}
+	exclude, err := parseProducts(splitList(p.exclude))
+	if err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "invalid --exclude-products: %v\n", err)
+		return false
+	}
+	applyProducts(values, include, exclude)
+	return true
+}
+
+// parseProducts maps product names and aliases, in any case, to API names.
+func parseProducts(names []string) ([]string, error) {
+	products := make([]string, 0, len(names))
+	for _, name := range names {
+		product, ok := productName(name)
+		if !ok {
+			aliases := make([]string, len(productAliases))
+			for i, a := range productAliases {
+				aliases[i] = a.alias
+			}
+			return nil, fmt.Errorf("unknown product %q (expected %s or %s)", name, strings.Join(aliases, ", "), strings.Join(apiProducts, ", "))
+		}
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+func productName(name string) (string, bool) {
+	key := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "-", ""))
+	for _, a := range productAliases {
+		if a.alias == key {
+			return a.product, true
+		}
+	}
+	for _, product := range apiProducts {
+		if strings.ToLower(product) == key {
+			return product, true
+		}
+	}
+	return "", false
+}
+
+// applyProducts enables exactly the included products, or all but the
+// excluded ones when nothing is included. Toggles already present in
+// values (e.g. from --param) are left alone. Empty lists are a no-op.
+func applyProducts(values url.Values, include, exclude []string) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return
+	}
+	enabled := map[string]bool{}
+	for _, name := range include {
+		enabled[name] = true
+	}
+	excluded := map[string]bool{}
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	for _, product := range apiProducts {
+		if values.Has(product) {
+			continue
+		}
+		switch {
+		case excluded[product]:
+			values.Set(product, "false")
+		case len(include) > 0:
+			values.Set(product, fmt.Sprintf("%t", enabled[product]))
+		}
+	}
+}
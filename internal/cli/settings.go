@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/config"
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+// setting is one resolved global value and where it came from.
+type setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// resolver applies the precedence flags > env > profile > defaults.
+type resolver struct {
+	getenv  func(string) string
+	name    string
+	source  string
+	profile config.Profile
+}
+
+// newResolver selects the profile named by --profile, DBREST_PROFILE, the
+// config's top-level "profile" key or, if present, the "default" profile.
+func newResolver(conf config.File, flagProfile string, getenv func(string) string) (*resolver, error) {
+	res := &resolver{getenv: getenv}
+	switch {
+	case flagProfile != "":
+		res.name, res.source = flagProfile, "flag"
+	case envOrDefault(getenv, "DBREST_PROFILE", "") != "":
+		res.name, res.source = envOrDefault(getenv, "DBREST_PROFILE", ""), "env DBREST_PROFILE"
+	case conf.DefaultProfile != "":
+		res.name, res.source = conf.DefaultProfile, "config"
+	default:
+		if _, ok := conf.Profiles["default"]; ok {
+			res.name, res.source = "default", "default"
+		}
+		return res, nil
+	}
+	profile, ok := conf.Profiles[res.name]
+	if !ok {
+		known := strings.Join(conf.ProfileNames(), ", ")
+		if known == "" {
+			known = "none"
+		}
+		return nil, fmt.Errorf("unknown profile %q (configured: %s)", res.name, known)
+	}
+	res.profile = profile
+	return res, nil
+}
+
+func (r *resolver) profileSetting() setting {
+	if r.name == "" {
+		return setting{Key: "profile", Value: "-", Source: "default"}
+	}
+	return setting{Key: "profile", Value: r.name, Source: r.source}
+}
+
+func (r *resolver) resolve(key, flagValue string, flagSet bool, envKey, profileValue, fallback string) setting {
+	if flagSet {
+		return setting{Key: key, Value: flagValue, Source: "flag"}
+	}
+	if envKey != "" {
+		if value := envOrDefault(r.getenv, envKey, ""); value != "" {
+			return setting{Key: key, Value: value, Source: "env " + envKey}
+		}
+	}
+	if profileValue != "" {
+		return setting{Key: key, Value: profileValue, Source: "profile " + r.name}
+	}
+	return setting{Key: key, Value: fallback, Source: "default"}
+}
+
+// resultsDefault returns the profile's results value or fallback.
+func (s *session) resultsDefault(fallback int) int {
+	if s.profile.Results != nil {
+		return *s.profile.Results
+	}
+	return fallback
+}
+
+func runConfig(args []string, sess *session) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var helpFlag bool
+	fs.BoolVar(&helpFlag, "help", false, "Show help")
+	fs.BoolVar(&helpFlag, "h", false, "Show help (shorthand)")
+
+	if sess.describe(fs) {
+		return exitOK
+	}
+
+	if err := fs.Parse(args); err != nil {
+		_, _ = fmt.Fprintln(sess.errOut, err)
+		printConfigUsage(sess.errOut)
+		return exitUsage
+	}
+	if helpFlag {
+		printConfigUsage(sess.out)
+		return exitOK
+	}
+	if fs.NArg() == 0 || fs.Arg(0) != "show" {
+		if fs.NArg() > 0 {
+			_, _ = fmt.Fprintf(sess.errOut, "unknown config command: %s\n", fs.Arg(0))
+		}
+		printConfigUsage(sess.errOut)
+		return exitUsage
+	}
+
+	switch sess.mode {
+	case OutputJSON:
+		data, err := json.Marshal(sess.settings)
+		if err != nil {
+			_, _ = fmt.Fprintln(sess.errOut, err)
+			return exitError
+		}
+		writeJSON(sess.out, data)
+	default:
+		t := format.Table{Columns: []format.Column{{Name: "key"}, {Name: "value"}, {Name: "source"}}}
+		for _, st := range sess.settings {
+			value := st.Value
+			if value == "" {
+				value = "-"
+			}
+			t.Rows = append(t.Rows, format.Row{Cells: []string{st.Key, value, st.Source}})
+		}
+		return writeTable(sess, t)
+	}
+	return exitOK
+}
+
+func formatOptionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func printConfigUsage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `USAGE:
+  dbrest config show
+
+COMMANDS:
+  show           Print the effective settings and where each came from
+
+CONFIG FILE:
+  $XDG_CONFIG_HOME/dbrest/config.toml (default: ~/.config/dbrest/config.toml)
+
+    profile = "work"            # profile used when --profile is not given
+
+    [profiles.work]
+    base_url = "https://v6.db.transport.rest"
+    timeout = "20s"
+    output = "plain"            # human, plain, json, csv, tsv, ndjson, geojson or ics
+    results = 5                 # default --results
+    products = ["regional", "suburban"]
+
+  Precedence: flags > env > profile > defaults. Without --profile,
+  DBREST_PROFILE or a top-level "profile" key, a profile named "default"
+  is used if it exists.
+  --plain prints: key, value, source.
+
+EXAMPLE:
+  dbrest --profile work config show`)
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+const defaultTerminalWidth = 80
+
+// tableOptions decides how human output is rendered on out. Width fitting
+// and colour only apply to terminals; NO_COLOR disables colour.
+func tableOptions(out io.Writer, getenv func(string) string) format.TableOptions {
+	if !isTerminalWriter(out) {
+		return format.TableOptions{}
+	}
+	return format.TableOptions{
+		Width: terminalWidth(out.(*os.File), getenv),
+		Color: getenv("NO_COLOR") == "",
+	}
+}
+
+func isTerminalWriter(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	return ok && isTerminal(file)
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func terminalWidth(file *os.File, getenv func(string) string) int {
+	if cols, err := strconv.Atoi(envOrDefault(getenv, "COLUMNS", "")); err == nil && cols > 0 {
+		return cols
+	}
+	if cols := ioctlWidth(file); cols > 0 {
+		return cols
+	}
+	return defaultTerminalWidth
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !linux
+
+package cli
+
+import "os"
+
+func ioctlWidth(*os.File) int {
+	return 0
+}
@@ -0,0 +1,20 @@
+//go:build darwin || linux
+
+package cli
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func ioctlWidth(file *os.File) int {
+	var ws struct {
+		Row, Col, X, Y uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.Col)
+}
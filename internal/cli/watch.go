@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/api"
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+const (
+	minWatchInterval = time.Second
+	maxWatchBackoff  = 5 * time.Minute
+
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+// runWatch repeats a request every interval until SIGINT. Human output is
+// redrawn in place; line-based modes only print rows that changed since
+// the previous poll. Failed polls double the wait up to maxWatchBackoff.
+// Polls bypass the response cache.
+func runWatch(sess *session, interval time.Duration, path string, values url.Values, formatter func([]byte) (format.Table, error)) int {
+	if interval < minWatchInterval {
+		_, _ = fmt.Fprintf(sess.errOut, "--watch must be at least %s\n", minWatchInterval)
+		return exitUsage
+	}
+	ctx, stop := signal.NotifyContext(sess.ctx, os.Interrupt)
+	defer stop()
+	watched := *sess
+	watched.ctx = ctx
+	watched.client = sess.live
+
+	var previous map[string]bool
+	wait := interval
+	for {
+		data, err := fetch(&watched, path, values)
+		if ctx.Err() != nil {
+			return exitOK
+		}
+		if err == nil {
+			previous, err = emitWatch(&watched, data, formatter, interval, previous)
+			if errors.Is(err, format.ErrUnknownField) {
+				_, _ = fmt.Fprintln(sess.errOut, err)
+				return exitUsage
+			}
+			if err != nil {
+				_, _ = fmt.Fprintf(sess.errOut, "formatting error: %v\n", err)
+			}
+		}
+		if err != nil {
+			wait = min(wait*2, maxWatchBackoff)
+			_, _ = fmt.Fprintf(sess.errOut, "retrying in %s\n", wait)
+		} else {
+			wait = interval
+		}
+		if api.SleepContext(ctx, wait) != nil {
+			return exitOK
+		}
+	}
+}
+
+// emitWatch writes one poll and returns the rows to diff the next poll against.
+func emitWatch(sess *session, data []byte, formatter func([]byte) (format.Table, error), interval time.Duration, previous map[string]bool) (map[string]bool, error) {
+	var rows []string
+	switch sess.mode {
+	case OutputJSON:
+		items, err := format.RawItems(data)
+		if err != nil {
+			return previous, err
+		}
+		for _, item := range items {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, item); err != nil {
+				return previous, err
+			}
+			rows = append(rows, compact.String())
+		}
+	case OutputGeoJSON:
+		table, err := formatter(data)
+		if err == nil {
+			table, err = table.Select(sess.fields)
+		}
+		if err != nil {
+			return previous, err
+		}
+		table = sess.formatTimes(table)
+		rows = append(rows, table.GeoJSON())
+	case OutputPlain, OutputCSV, OutputTSV, OutputNDJSON, OutputTemplate:
+		table, err := formatter(data)
+		if err == nil {
+			table, err = table.Select(sess.fields)
+		}
+		if err != nil {
+			return previous, err
+		}
+		table = sess.formatTimes(table)
+		if previous == nil && sess.header && sess.mode != OutputPlain {
+			writeTable(sess, format.Table{Columns: table.Columns})
+		}
+		for _, row := range table.Rows {
+			one := format.Table{Columns: table.Columns, Rows: []format.Row{row}}
+			switch sess.mode {
+			case OutputCSV:
+				rows = append(rows, one.CSV(false))
+			case OutputTSV:
+				rows = append(rows, one.TSV(false))
+			case OutputNDJSON:
+				rows = append(rows, one.NDJSON())
+			case OutputTemplate:
+				line, err := one.Template(sess.template)
+				if err != nil {
+					return previous, err
+				}
+				rows = append(rows, line)
+			default:
+				rows = append(rows, one.Plain(false))
+			}
+		}
+	default:
+		table, err := formatter(data)
+		if err == nil {
+			table, err = table.Select(sess.fields)
+		}
+		if err != nil {
+			return previous, err
+		}
+		table = sess.formatTimes(table)
+		header := fmt.Sprintf("every %s, updated %s\n\n", interval, time.Now().Format("15:04:05"))
+		if sess.terminal {
+			header = clearScreen + header
+		}
+		_, _ = fmt.Fprint(sess.out, header+table.Render(sess.table))
+		if !sess.terminal {
+			_, _ = fmt.Fprintln(sess.out)
+		}
+		return previous, nil
+	}
+
+	current := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		row = strings.TrimSuffix(row, "\n")
+		if row == "" {
+			continue
+		}
+		current[row] = true
+		if !previous[row] {
+			_, _ = fmt.Fprintln(sess.out, row)
+		}
+	}
+	return current, nil
+}
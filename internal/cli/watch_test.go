@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timkrase/deutsche-bahn-skill/internal/format"
+)
+
+func TestEmitWatchPlainPrintsChangedRows(t *testing.T) {
+	out := &bytes.Buffer{}
+	sess := &session{ctx: context.Background(), out: out, errOut: &bytes.Buffer{}, mode: OutputPlain}
+
+	first := []byte(`[{"when":"12:00","line":{"name":"S1"},"direction":"Frohnau","delay":0},{"when":"12:10","line":{"name":"S2"},"direction":"Bernau","delay":0}]`)
+	second := []byte(`[{"when":"12:00","line":{"name":"S1"},"direction":"Frohnau","delay":0},{"when":"12:13","line":{"name":"S2"},"direction":"Bernau","delay":180}]`)
+
+	previous, err := emitWatch(sess, first, format.StopoversTable, time.Minute, map[string]bool{})
+	if err != nil {
+		t.Fatalf("emitWatch error: %v", err)
+	}
+	out.Reset()
+	if _, err := emitWatch(sess, second, format.StopoversTable, time.Minute, previous); err != nil {
+		t.Fatalf("emitWatch error: %v", err)
+	}
+	if out.String() != "12:13\tS2\tBernau\t-\t+3m\t-\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestEmitWatchJSONPrintsChangedItems(t *testing.T) {
+	out := &bytes.Buffer{}
+	sess := &session{ctx: context.Background(), out: out, errOut: &bytes.Buffer{}, mode: OutputJSON}
+
+	previous, err := emitWatch(sess, []byte(`{"movements":[{"direction":"A"}, {"direction":"B"}]}`), format.RadarTable, time.Minute, map[string]bool{})
+	if err != nil {
+		t.Fatalf("emitWatch error: %v", err)
+	}
+	if out.String() != "{\"direction\":\"A\"}\n{\"direction\":\"B\"}\n" {
+		t.Fatalf("unexpected first output: %q", out.String())
+	}
+	out.Reset()
+	if _, err := emitWatch(sess, []byte(`{"movements":[{"direction":"A"},{"direction":"C"}]}`), format.RadarTable, time.Minute, previous); err != nil {
+		t.Fatalf("emitWatch error: %v", err)
+	}
+	if out.String() != "{\"direction\":\"C\"}\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestRunWatchRejectsShortInterval(t *testing.T) {
+	exit, _, stderr := runWith(&fakeClient{response: []byte(`[]`)}, "departures", "--watch", "10ms", "8011160")
+	if exit != exitUsage {
+		t.Fatalf("expected exit %d, got %d (%s)", exitUsage, exit, stderr)
+	}
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeZone is where relative times are resolved without --tz.
+const defaultTimeZone = "Europe/Berlin"
+
+// whenHint lists the accepted --when/--departure/--arrival forms.
+const whenHint = "now, +30m, in 2h, 18:30, tomorrow 07:45, mon 08:00 or ISO 8601"
+
+// dayWords maps English and German relative day names to a day offset.
+var dayWords = map[string]int{
+	"today": 0, "heute": 0,
+	"tomorrow": 1, "morgen": 1,
+	"übermorgen": 2, "uebermorgen": 2,
+}
+
+var weekdayWords = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday, "so": time.Sunday, "sonntag": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday, "mo": time.Monday, "montag": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday, "di": time.Tuesday, "dienstag": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "mi": time.Wednesday, "mittwoch": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday, "do": time.Thursday, "donnerstag": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "fr": time.Friday, "freitag": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "sa": time.Saturday, "samstag": time.Saturday,
+}
+
+// errNegativeSpan rejects "+-5m" and "in -5m": relative times only look
+// ahead.
+var errNegativeSpan = errors.New("the duration must not be negative")
+
+// durationUnits maps spelled-out units to Go duration units.
+var durationUnits = map[string]string{
+	"m": "m", "min": "m", "mins": "m", "minute": "m", "minutes": "m", "minuten": "m",
+	"h": "h", "hour": "h", "hours": "h", "std": "h", "stunde": "h", "stunden": "h",
+}
+
+// setTime resolves a --when/--departure/--arrival value and sets it as
+// values[key] in RFC 3339. An unparseable value is reported to stderr.
+func setTime(sess *session, values url.Values, key, value string) bool {
+	if value == "" {
+		return true
+	}
+	at, err := parseWhen(value, time.Now(), sess.tz)
+	if errors.Is(err, errNegativeSpan) {
+		_, _ = fmt.Fprintf(sess.errOut, "invalid --%s %q: %v\n", key, value, err)
+		return false
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(sess.errOut, "invalid --%s %q (expected %s)\n", key, value, whenHint)
+		return false
+	}
+	resolved := at.Format(time.RFC3339)
+	if sess.verbose {
+		_, _ = fmt.Fprintf(sess.errOut, "%s: %s (from %q)\n", key, resolved, value)
+	}
+	values.Set(key, resolved)
+	return true
+}
+
+// parseWhen resolves a natural or ISO 8601 time relative to now in loc.
+// Weekdays mean the next such day and a bare clock time the next such
+// time, today only if it is still ahead.
+func parseWhen(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	now = now.In(loc)
+	text := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	if at, ok := parseISO(text, loc); ok {
+		return at, nil
+	}
+	if text == "now" || text == "jetzt" {
+		return now, nil
+	}
+	if rest, ok := strings.CutPrefix(text, "+"); ok {
+		d, err := parseSpan(rest)
+		return now.Add(d), err
+	}
+	if rest, ok := strings.CutPrefix(text, "in "); ok {
+		d, err := parseSpan(rest)
+		return now.Add(d), err
+	}
+
+	words := strings.Fields(text)
+	day := now
+	dayGiven, isWeekday := false, false
+	if len(words) > 0 {
+		if offset, ok := dayWords[words[0]]; ok {
+			day = now.AddDate(0, 0, offset)
+			words, dayGiven = words[1:], true
+		} else if weekday, ok := weekdayWords[strings.TrimSuffix(words[0], ".")]; ok {
+			day = now.AddDate(0, 0, (int(weekday)-int(now.Weekday())+7)%7)
+			words, dayGiven, isWeekday = words[1:], true, true
+		}
+	}
+	if len(words) > 0 && (words[0] == "at" || words[0] == "um") {
+		words = words[1:]
+	}
+	if len(words) > 1 && words[len(words)-1] == "uhr" {
+		words = words[:len(words)-1]
+	}
+	switch {
+	case len(words) == 0 && dayGiven:
+		return day, nil
+	case len(words) != 1:
+		return time.Time{}, fmt.Errorf("unrecognised time %q", value)
+	}
+	hour, minute, err := parseClock(words[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+	at := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+	switch {
+	case isWeekday && at.Before(now):
+		at = at.AddDate(0, 0, 7)
+	case !dayGiven && at.Before(now):
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
+
+// parseISO accepts RFC 3339 and the same without seconds or offset, which
+// is taken to be in loc.
+func parseISO(text string, loc *time.Location) (time.Time, bool) {
+	upper := strings.ToUpper(text)
+	if at, err := time.Parse(time.RFC3339, upper); err == nil {
+		return at.In(loc), true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05Z07:00", "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if at, err := time.ParseInLocation(layout, upper, loc); err == nil {
+			return at.In(loc), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseSpan parses "30m", "1h30m", "2 h" or "30 min".
+func parseSpan(text string) (time.Duration, error) {
+	compact := strings.ReplaceAll(text, " ", "")
+	if strings.HasPrefix(compact, "-") {
+		return 0, errNegativeSpan
+	}
+	if d, err := time.ParseDuration(compact); err == nil {
+		return d, nil
+	}
+	number, unit := compact, ""
+	for i, r := range compact {
+		if r < '0' || r > '9' {
+			number, unit = compact[:i], compact[i:]
+			break
+		}
+	}
+	n, err := strconv.Atoi(number)
+	goUnit, ok := durationUnits[unit]
+	if err != nil || !ok {
+		return 0, fmt.Errorf("unrecognised duration %q", text)
+	}
+	return time.ParseDuration(strconv.Itoa(n) + goUnit)
+}
+
+// parseClock parses "7:45", "07:45" or "7.45".
+func parseClock(text string) (int, int, error) {
+	hh, mm, ok := strings.Cut(strings.Replace(text, ".", ":", 1), ":")
+	if !ok {
+		hh, mm = text, "0"
+	}
+	hour, errHour := strconv.Atoi(hh)
+	minute, errMinute := strconv.Atoi(mm)
+	if errHour != nil || errMinute != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 || len(mm) > 2 {
+		return 0, 0, fmt.Errorf("unrecognised clock time %q", text)
+	}
+	return hour, minute, nil
+}
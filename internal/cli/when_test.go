@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseWhen(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A Wednesday.
+	now := time.Date(2024, 2, 7, 9, 15, 30, 0, berlin)
+
+	cases := map[string]string{
+		"now":                       "2024-02-07T09:15:30+01:00",
+		"+30m":                      "2024-02-07T09:45:30+01:00",
+		"in 2h":                     "2024-02-07T11:15:30+01:00",
+		"in 45 min":                 "2024-02-07T10:00:30+01:00",
+		"18:30":                     "2024-02-07T18:30:00+01:00",
+		"08:00":                     "2024-02-08T08:00:00+01:00",
+		"heute 08:00":               "2024-02-07T08:00:00+01:00",
+		"tomorrow 07:45":            "2024-02-08T07:45:00+01:00",
+		"morgen 7:45":               "2024-02-08T07:45:00+01:00",
+		"Mon 08:00":                 "2024-02-12T08:00:00+01:00",
+		"wed 08:00":                 "2024-02-14T08:00:00+01:00",
+		"mi um 10:00 Uhr":           "2024-02-07T10:00:00+01:00",
+		"2024-03-31T12:00":          "2024-03-31T12:00:00+02:00",
+		"2024-02-01T08:00:00+00:00": "2024-02-01T09:00:00+01:00",
+	}
+	for input, want := range cases {
+		got, err := parseWhen(input, now, berlin)
+		if err != nil {
+			t.Errorf("parseWhen(%q): %v", input, err)
+			continue
+		}
+		if got.Format(time.RFC3339) != want {
+			t.Errorf("parseWhen(%q) = %s, want %s", input, got.Format(time.RFC3339), want)
+		}
+	}
+
+	for _, input := range []string{"", "later", "25:00", "tomorrow at noon", "in a bit"} {
+		if _, err := parseWhen(input, now, berlin); err == nil {
+			t.Errorf("parseWhen(%q): expected an error", input)
+		}
+	}
+	for _, input := range []string{"+-5m", "in -5m", "in - 5 min"} {
+		if _, err := parseWhen(input, now, berlin); !errors.Is(err, errNegativeSpan) {
+			t.Errorf("parseWhen(%q): expected errNegativeSpan, got %v", input, err)
+		}
+	}
+}
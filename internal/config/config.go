@@ -0,0 +1,185 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile holds the defaults of one named profile. Empty fields are unset.
+type Profile struct {
+	BaseURL  string
+	Timeout  string
+	Output   string
+	Results  *int
+	Products []string
+}
+
+// File is the parsed config file.
+type File struct {
+	// Path is where the file was loaded from, even if it does not exist.
+	Path string
+	// DefaultProfile is the top-level "profile" key.
+	DefaultProfile string
+	Profiles       map[string]Profile
+	// Aliases maps alias names (without "@") to stop ids or names: one
+	// stop, or several for a group saved as an array.
+	Aliases map[string][]string
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/dbrest/config.toml, falling back to
+// ~/.config/dbrest/config.toml. It is empty if neither variable is set.
+func DefaultPath(getenv func(string) string) string {
+	if base := strings.TrimSpace(getenv("XDG_CONFIG_HOME")); base != "" {
+		return filepath.Join(base, "dbrest", "config.toml")
+	}
+	if home := strings.TrimSpace(getenv("HOME")); home != "" {
+		return filepath.Join(home, ".config", "dbrest", "config.toml")
+	}
+	return ""
+}
+
+// Load reads and parses the config file at path. A missing file is not an
+// error and yields an empty File.
+func Load(path string) (File, error) {
+	file := File{Path: path, Profiles: map[string]Profile{}, Aliases: map[string][]string{}}
+	if path == "" {
+		return file, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, err
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		return file, fmt.Errorf("%s: %w", path, err)
+	}
+	parsed.Path = path
+	return parsed, nil
+}
+
+// Parse decodes config TOML:
+//
+//	profile = "work"
+//
+//	[profiles.work]
+//	base_url = "https://v6.db.transport.rest"
+//	timeout = "20s"
+//	output = "plain"
+//	results = 5
+//	products = ["regional", "suburban"]
+//
+//	[aliases]
+//	home = "8011160"
+//	office = ["900100003", "900003201"]
+func Parse(data []byte) (File, error) {
+	file := File{Profiles: map[string]Profile{}, Aliases: map[string][]string{}}
+	tables, err := parseTOML(string(data))
+	if err != nil {
+		return file, err
+	}
+	for _, name := range sortedKeys(tables) {
+		keys := tables[name]
+		switch {
+		case name == "":
+			for key, value := range keys {
+				switch key {
+				case "profile":
+					s, ok := value.(string)
+					if !ok {
+						return file, fmt.Errorf("profile must be a string")
+					}
+					file.DefaultProfile = s
+				default:
+					return file, fmt.Errorf("unknown key %q", key)
+				}
+			}
+		case name == "profiles":
+			if len(keys) > 0 {
+				return file, errors.New("[profiles] must only contain [profiles.<name>] tables")
+			}
+		case strings.HasPrefix(name, "profiles."):
+			parts, err := splitDotted(name)
+			if err != nil || len(parts) != 2 {
+				return file, fmt.Errorf("unknown table [%s]", name)
+			}
+			profileName := parts[1]
+			profile, err := decodeProfile(keys)
+			if err != nil {
+				return file, fmt.Errorf("[%s]: %w", name, err)
+			}
+			file.Profiles[profileName] = profile
+		case name == "aliases":
+			for key, value := range keys {
+				switch v := value.(type) {
+				case string:
+					file.Aliases[key] = []string{v}
+				case []string:
+					if len(v) == 0 {
+						return file, fmt.Errorf("[aliases]: %q must not be empty", key)
+					}
+					file.Aliases[key] = v
+				default:
+					return file, fmt.Errorf("[aliases]: %q must be a string or an array of strings", key)
+				}
+			}
+		default:
+			return file, fmt.Errorf("unknown table [%s]", name)
+		}
+	}
+	return file, nil
+}
+
+func decodeProfile(keys map[string]any) (Profile, error) {
+	var profile Profile
+	for key, value := range keys {
+		var ok bool
+		switch key {
+		case "base_url":
+			profile.BaseURL, ok = value.(string)
+		case "timeout":
+			profile.Timeout, ok = value.(string)
+		case "output":
+			profile.Output, ok = value.(string)
+		case "results":
+			var n int64
+			n, ok = value.(int64)
+			results := int(n)
+			profile.Results = &results
+		case "products":
+			profile.Products, ok = value.([]string)
+		default:
+			return profile, fmt.Errorf("unknown key %q", key)
+		}
+		if !ok {
+			return profile, fmt.Errorf("invalid value for %q", key)
+		}
+	}
+	return profile, nil
+}
+
+// ProfileNames returns the configured profile names in sorted order.
+func (f File) ProfileNames() []string {
+	return sortedKeys(f.Profiles)
+}
+
+// AliasNames returns the configured alias names in sorted order.
+func (f File) AliasNames() []string {
+	return sortedKeys(f.Aliases)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseProfiles(t *testing.T) {
+	data := []byte(`# dbrest config
+profile = "work"
+
+[profiles.work]
+base_url = "http://localhost:3000" # local instance
+timeout = '20s'
+output = "plain"
+results = 5
+products = ["regional", "suburban"]
+
+[profiles.home]
+timeout = "5s"
+`)
+	file, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if file.DefaultProfile != "work" {
+		t.Fatalf("expected default profile work, got %q", file.DefaultProfile)
+	}
+	work := file.Profiles["work"]
+	if work.BaseURL != "http://localhost:3000" || work.Timeout != "20s" || work.Output != "plain" {
+		t.Fatalf("unexpected profile: %+v", work)
+	}
+	if work.Results == nil || *work.Results != 5 {
+		t.Fatalf("unexpected results: %v", work.Results)
+	}
+	if len(work.Products) != 2 || work.Products[1] != "suburban" {
+		t.Fatalf("unexpected products: %q", work.Products)
+	}
+	if file.Profiles["home"].Timeout != "5s" {
+		t.Fatalf("unexpected home profile: %+v", file.Profiles["home"])
+	}
+}
+
+func TestParseRejectsUnknownKeys(t *testing.T) {
+	if _, err := Parse([]byte("[profiles.work]\nbaseurl = \"x\"\n")); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+	if _, err := Parse([]byte("[profiles.work]\nresults = \"five\"\n")); err == nil {
+		t.Fatal("expected error for invalid value")
+	}
+}
+
+func TestParseQuotedNamesAndEscapes(t *testing.T) {
+	data := []byte(`[profiles."my office"]
+base_url = "http://host/\u00e4\tx"
+
+[profiles.'home']
+timeout = "5s"
+
+[aliases]
+"work-stop" = "Berlin \"Hbf\""
+`)
+	file, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if got := file.Profiles["my office"].BaseURL; got != "http://host/\u00e4\tx" {
+		t.Fatalf("unexpected base_url %q", got)
+	}
+	if file.Profiles["home"].Timeout != "5s" {
+		t.Fatalf("unexpected home profile: %+v", file.Profiles["home"])
+	}
+	if got := file.Aliases["work-stop"]; !slices.Equal(got, []string{`Berlin "Hbf"`}) {
+		t.Fatalf("unexpected alias %q", got)
+	}
+}
+
+func TestParseRejectsUnsupportedSyntax(t *testing.T) {
+	for _, text := range []string{
+		"[aliases]\nhome = \"\\x41\"\n",
+		"[aliases]\nhome = \"\\a\"\n",
+		"[profiles.work.extra]\n",
+		"[aliases]\nhome = \"\"\"x\"\"\"\n",
+		"[aliases]\nhome = 1.5\n",
+		"[aliases]\nhome = []\n",
+	} {
+		if _, err := Parse([]byte(text)); err == nil {
+			t.Fatalf("expected an error for %q", text)
+		}
+	}
+	_, err := Parse([]byte("[aliases]\nhome = \"\\x41\"\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Fatalf("expected an error for line 2, got %v", err)
+	}
+}
+
+func TestSetAndRemoveAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	initial := "# my stops\n[aliases]\nhome = \"1\" # old\n\n[profiles.work]\nresults = 5\n"
+	if err := os.WriteFile(path, []byte(initial), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAlias(path, "home", "8011160"); err != nil {
+		t.Fatalf("SetAlias error: %v", err)
+	}
+	if err := SetAlias(path, "work", "8002549"); err != nil {
+		t.Fatalf("SetAlias error: %v", err)
+	}
+	if err := SetAlias(path, "mitte", "Berlin, Invalidenpark", "900100003"); err != nil {
+		t.Fatalf("SetAlias error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# my stops\n[aliases]\nhome = \"8011160\"\nwork = \"8002549\"\nmitte = [\"Berlin, Invalidenpark\", \"900100003\"]\n\n[profiles.work]\nresults = 5\n"
+	if string(data) != expected {
+		t.Fatalf("unexpected file:\n%s", data)
+	}
+
+	if err := RemoveAlias(path, "home"); err != nil {
+		t.Fatalf("RemoveAlias error: %v", err)
+	}
+	file, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Aliases) != 2 || !slices.Equal(file.Aliases["work"], []string{"8002549"}) ||
+		!slices.Equal(file.Aliases["mitte"], []string{"Berlin, Invalidenpark", "900100003"}) {
+		t.Fatalf("unexpected aliases: %v", file.Aliases)
+	}
+	if err := RemoveAlias(path, "home"); err == nil {
+		t.Fatal("expected error for unknown alias")
+	}
+}
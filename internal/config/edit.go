@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const aliasesHeader = "[aliases]"
+
+// ValidAliasName reports whether name can be used as an alias: letters,
+// digits, '-' and '_'.
+func ValidAliasName(name string) bool {
+	return isBareKey(name)
+}
+
+// SetAlias adds or replaces an alias in the config file at path, keeping
+// the rest of the file, including comments, untouched. Several stops are
+// saved as an array, a group.
+func SetAlias(path, name string, stops ...string) error {
+	if !ValidAliasName(name) {
+		return fmt.Errorf("invalid alias name %q (use letters, digits, - and _)", name)
+	}
+	if len(stops) == 0 {
+		return errors.New("an alias needs at least one stop")
+	}
+	value := quoteBasic(stops[0])
+	if len(stops) > 1 {
+		quoted := make([]string, len(stops))
+		for i, stop := range stops {
+			quoted[i] = quoteBasic(stop)
+		}
+		value = "[" + strings.Join(quoted, ", ") + "]"
+	}
+	return editFile(path, func(lines []string) ([]string, error) {
+		entry := name + " = " + value
+		start, end := aliasesSection(lines)
+		if start < 0 {
+			if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+				lines = append(lines, "")
+			}
+			return append(lines, aliasesHeader, entry), nil
+		}
+		if i := findKey(lines, start, end, name); i >= 0 {
+			lines[i] = entry
+			return lines, nil
+		}
+		insert := start + 1
+		for i := start + 1; i < end; i++ {
+			if strings.TrimSpace(lines[i]) != "" {
+				insert = i + 1
+			}
+		}
+		return slices.Insert(lines, insert, entry), nil
+	})
+}
+
+// RemoveAlias deletes an alias from the config file at path.
+func RemoveAlias(path, name string) error {
+	return editFile(path, func(lines []string) ([]string, error) {
+		start, end := aliasesSection(lines)
+		i := -1
+		if start >= 0 {
+			i = findKey(lines, start, end, name)
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("unknown alias %q", name)
+		}
+		return slices.Delete(lines, i, i+1), nil
+	})
+}
+
+// editFile applies edit to the lines of the file at path and writes the
+// result atomically, refusing to write a file that no longer parses.
+func editFile(path string, edit func([]string) ([]string, error)) error {
+	if path == "" {
+		return errors.New("config path unknown: set XDG_CONFIG_HOME or HOME")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	var lines []string
+	if text := strings.TrimSuffix(string(data), "\n"); text != "" {
+		lines = strings.Split(text, "\n")
+	}
+	lines, err = edit(lines)
+	if err != nil {
+		return err
+	}
+	out := strings.Join(lines, "\n") + "\n"
+	if _, err := Parse([]byte(out)); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.WriteString(out); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// aliasesSection returns the header line of the [aliases] table and the
+// index of the line after its last entry, or -1 if there is no such table.
+func aliasesSection(lines []string) (int, int) {
+	start := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(stripComment(line))
+		if start < 0 {
+			if trimmed == aliasesHeader {
+				start = i
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			return start, i
+		}
+	}
+	return start, len(lines)
+}
+
+func findKey(lines []string, start, end int, name string) int {
+	for i := start + 1; i < end; i++ {
+		key, _, ok := strings.Cut(stripComment(lines[i]), "=")
+		if !ok {
+			continue
+		}
+		if parsed, err := parseKey(strings.TrimSpace(key)); err == nil && parsed == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,316 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML decodes the TOML subset used by the config file: [table]
+// headers with dotted names, key = value pairs and # comments. Values may
+// be strings, integers, booleans or single-line arrays of strings. Tables
+// are keyed by their dotted name, with parts that are not bare keys
+// quoted (see joinDotted). Keys before the first header belong to the ""
+// table. Anything else is an error naming the line.
+func parseTOML(text string) (map[string]map[string]any, error) {
+	tables := map[string]map[string]any{"": {}}
+	current := ""
+	for i, raw := range strings.Split(text, "\n") {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineNo, line)
+			}
+			if strings.TrimSpace(line[1:len(line)-1]) == "" {
+				return nil, fmt.Errorf("line %d: empty table name", lineNo)
+			}
+			parts, err := splitDotted(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			name := joinDotted(parts)
+			if _, exists := tables[name]; exists {
+				return nil, fmt.Errorf("line %d: duplicate table [%s]", lineNo, name)
+			}
+			tables[name] = map[string]any{}
+			current = name
+			continue
+		}
+		key, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key, err := parseKey(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		value, err := parseValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if _, exists := tables[current][key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+		}
+		tables[current][key] = value
+	}
+	return tables, nil
+}
+
+func parseKey(key string) (string, error) {
+	if strings.HasPrefix(key, `"`) || strings.HasPrefix(key, "'") {
+		parsed, rest, err := cutString(key)
+		if err != nil {
+			return "", err
+		}
+		if rest != "" {
+			return "", fmt.Errorf("unsupported dotted key %q", key)
+		}
+		return parsed, nil
+	}
+	if key == "" {
+		return "", fmt.Errorf("empty key")
+	}
+	if !isBareKey(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return key, nil
+}
+
+func isBareKey(key string) bool {
+	for _, r := range key {
+		if !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return key != ""
+}
+
+func parseValue(value string) (any, error) {
+	switch {
+	case value == "":
+		return nil, fmt.Errorf("missing value")
+	case value == "true":
+		return true, nil
+	case value == "false":
+		return false, nil
+	case strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''"):
+		return nil, fmt.Errorf("multi-line strings are not supported")
+	case strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'"):
+		s, rest, err := cutString(value)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %s after string", strings.TrimSpace(rest))
+		}
+		return s, nil
+	case strings.HasPrefix(value, "["):
+		return parseStringArray(value)
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported value %s (expected a string, integer, boolean or array of strings)", value)
+	}
+	return n, nil
+}
+
+func parseStringArray(value string) ([]string, error) {
+	if !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("arrays must be on one line")
+	}
+	inner := strings.TrimSpace(value[1 : len(value)-1])
+	items := []string{}
+	for inner != "" {
+		item, rest, err := cutString(inner)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			break
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return nil, fmt.Errorf("expected , in array %s", value)
+		}
+		inner = strings.TrimSpace(rest[1:])
+	}
+	return items, nil
+}
+
+// cutString reads one quoted string from the start of s.
+func cutString(s string) (string, string, error) {
+	if strings.HasPrefix(s, "'") {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("arrays may only contain strings: %s", s)
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			item, err := unescapeBasic(s[1:i])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s: %w", s[:i+1], err)
+			}
+			return item, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string %s", s)
+}
+
+// unescapeBasic resolves the escapes of a TOML basic string: \b, \t, \n,
+// \f, \r, \", \\, \uXXXX and \UXXXXXXXX.
+func unescapeBasic(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			if c < 0x20 && c != '\t' || c == 0x7f {
+				return "", fmt.Errorf("control character %U", rune(c))
+			}
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+		switch s[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case 'u', 'U':
+			size := 4
+			if s[i] == 'U' {
+				size = 8
+			}
+			if i+1+size > len(s) {
+				return "", fmt.Errorf("short \\%c escape", s[i])
+			}
+			code, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid \\%c escape", s[i])
+			}
+			b.WriteRune(rune(code))
+			i += size
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// quoteBasic writes s as a TOML basic string.
+func quoteBasic(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			_, _ = fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// splitDotted splits a dotted key such as profiles."my office" into its
+// bare or quoted parts.
+func splitDotted(s string) ([]string, error) {
+	var parts []string
+	for {
+		s = strings.TrimSpace(s)
+		var part string
+		if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+			quoted, rest, err := cutString(s)
+			if err != nil {
+				return nil, err
+			}
+			part, s = quoted, rest
+		} else {
+			end := strings.IndexByte(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			part, s = strings.TrimSpace(s[:end]), s[end:]
+			if !isBareKey(part) {
+				return nil, fmt.Errorf("invalid key %q", part)
+			}
+		}
+		parts = append(parts, part)
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return parts, nil
+		}
+		if s[0] != '.' {
+			return nil, fmt.Errorf("expected . before %q", s)
+		}
+		s = s[1:]
+	}
+}
+
+// joinDotted is the inverse of splitDotted, quoting only the parts that
+// are not bare keys.
+func joinDotted(parts []string) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = part
+		if !isBareKey(part) {
+			quoted[i] = quoteBasic(part)
+		}
+	}
+	return strings.Join(quoted, ".")
+}
+
+// stripComment removes a trailing # comment that is not inside a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,271 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownField is returned by Table.Select for a field the table does
+// not have.
+var ErrUnknownField = errors.New("unknown field")
+
+// Field registries: every column a table builder fills, in default order.
+// Extra columns are only shown when selected with Table.Select.
+var (
+	locationFields = []Column{
+		{Name: "id"},
+		{Name: "name"},
+		{Name: "type"},
+		{Name: "latitude", Kind: KindNumber},
+		{Name: "longitude", Kind: KindNumber},
+		{Name: "distance_m", Kind: KindNumber},
+		{Name: "products", Extra: true},
+	}
+	stopFields = []Column{
+		{Name: "id"},
+		{Name: "name"},
+		{Name: "type"},
+		{Name: "latitude", Kind: KindNumber},
+		{Name: "longitude", Kind: KindNumber},
+		{Name: "products"},
+		{Name: "lines"},
+		{Name: "facilities"},
+		{Name: "accessibility"},
+		{Name: "station_id", Extra: true},
+		{Name: "station_name", Extra: true},
+		{Name: "ril100", Extra: true},
+		{Name: "transit_authority", Extra: true},
+	}
+	reachableFields = []Column{
+		{Name: "duration", Kind: KindNumber},
+		{Name: "id"},
+		{Name: "name"},
+		{Name: "latitude", Kind: KindNumber},
+		{Name: "longitude", Kind: KindNumber},
+		{Name: "products", Extra: true},
+	}
+	stopoverFields = []Column{
+		{Name: "time", Kind: KindTime},
+		{Name: "line"},
+		{Name: "direction"},
+		{Name: "platform"},
+		{Name: "delay", Kind: KindDelay},
+		{Name: "status"},
+		{Name: "planned_when", Kind: KindTime, Extra: true},
+		{Name: "planned_platform", Extra: true},
+		{Name: "trip_id", Extra: true},
+		{Name: "product", Extra: true},
+		{Name: "operator", Extra: true},
+		{Name: "remarks", Extra: true},
+		{Name: "stop", Extra: true},
+		{Name: "stop_id", Extra: true},
+	}
+	journeyFields = []Column{
+		{Name: "departure", Kind: KindTime},
+		{Name: "origin"},
+		{Name: "arrival", Kind: KindTime},
+		{Name: "destination"},
+		{Name: "transfers", Kind: KindNumber},
+		{Name: "refresh_token", Extra: true},
+		{Name: "planned_departure", Kind: KindTime, Extra: true},
+		{Name: "planned_arrival", Kind: KindTime, Extra: true},
+		{Name: "duration", Kind: KindNumber, Extra: true},
+		{Name: "lines", Extra: true},
+	}
+	legFields = []Column{
+		{Name: "journey", Kind: KindNumber},
+		{Name: "line"},
+		{Name: "origin"},
+		{Name: "departure_platform"},
+		{Name: "planned_departure", Kind: KindTime},
+		{Name: "departure", Kind: KindTime},
+		{Name: "departure_delay", Kind: KindDelay},
+		{Name: "destination"},
+		{Name: "arrival_platform"},
+		{Name: "planned_arrival", Kind: KindTime},
+		{Name: "arrival", Kind: KindTime},
+		{Name: "arrival_delay", Kind: KindDelay},
+		{Name: "transfer", Kind: KindNumber},
+		{Name: "status"},
+		{Name: "refresh_token", Extra: true},
+		{Name: "trip_id", Extra: true},
+		{Name: "product", Extra: true},
+		{Name: "operator", Extra: true},
+		{Name: "direction", Extra: true},
+		{Name: "remarks", Extra: true},
+		{Name: "planned_departure_platform", Extra: true},
+		{Name: "planned_arrival_platform", Extra: true},
+	}
+	tripFields = []Column{
+		{Name: "line"},
+		{Name: "stop"},
+		{Name: "arrival", Kind: KindTime},
+		{Name: "departure", Kind: KindTime},
+		{Name: "platform"},
+		{Name: "stop_id", Extra: true},
+		{Name: "planned_arrival", Kind: KindTime, Extra: true},
+		{Name: "planned_departure", Kind: KindTime, Extra: true},
+		{Name: "planned_platform", Extra: true},
+		{Name: "arrival_delay", Kind: KindDelay, Extra: true},
+		{Name: "departure_delay", Kind: KindDelay, Extra: true},
+		{Name: "product", Extra: true},
+		{Name: "operator", Extra: true},
+	}
+	radarFields = []Column{
+		{Name: "line"},
+		{Name: "direction"},
+		{Name: "latitude", Kind: KindNumber},
+		{Name: "longitude", Kind: KindNumber},
+		{Name: "trip_id", Extra: true},
+		{Name: "product", Extra: true},
+		{Name: "operator", Extra: true},
+	}
+)
+
+var fieldUsage = map[string]string{
+	"accessibility":              "Accessibility facilities as key=value, e.g. stepFreeAccess=yes",
+	"arrival":                    "Arrival time (realtime if known)",
+	"arrival_delay":              "Arrival delay",
+	"arrival_platform":           "Arrival platform (realtime if known)",
+	"delay":                      "Delay",
+	"departure":                  "Departure time (realtime if known)",
+	"departure_delay":            "Departure delay",
+	"departure_platform":         "Departure platform (realtime if known)",
+	"destination":                "Destination stop",
+	"direction":                  "Direction of travel",
+	"distance_m":                 "Distance in metres",
+	"duration":                   "Travel time in minutes",
+	"facilities":                 "Other station facilities as key=value",
+	"id":                         "Location id",
+	"journey":                    "Number of the journey in the result",
+	"latitude":                   "Latitude",
+	"line":                       "Line name",
+	"lines":                      "Line names of all legs, or serving the stop",
+	"longitude":                  "Longitude",
+	"name":                       "Location name",
+	"operator":                   "Operator name",
+	"origin":                     "Origin stop",
+	"planned_arrival":            "Scheduled arrival time",
+	"planned_arrival_platform":   "Scheduled arrival platform",
+	"planned_departure":          "Scheduled departure time",
+	"planned_departure_platform": "Scheduled departure platform",
+	"planned_platform":           "Scheduled platform",
+	"planned_when":               "Scheduled time",
+	"platform":                   "Platform (realtime if known)",
+	"product":                    "Product, e.g. nationalExpress or suburban",
+	"products":                   "Products serving the stop",
+	"refresh_token":              "Token for dbrest journey refresh",
+	"remarks":                    "Remarks and warnings, separated by ';'",
+	"ril100":                     "RIL 100 station codes, e.g. BLS",
+	"status":                     "cancelled or -",
+	"stop":                       "Stop name",
+	"station_id":                 "Id of the station the stop belongs to",
+	"station_name":               "Name of the station the stop belongs to",
+	"stop_id":                    "Stop id",
+	"time":                       "Departure or arrival time (realtime if known)",
+	"transfer":                   "Wait since the previous leg arrived",
+	"transfers":                  "Number of transfers",
+	"trip_id":                    "Trip id for dbrest trip",
+	"transit_authority":          "Transit authority, e.g. VBB",
+	"type":                       "stop, station, address or poi",
+}
+
+// LocationFields returns the fields of LocationsTable.
+func LocationFields() []Column { return slices.Clone(locationFields) }
+
+// ReachableFields returns the fields of ReachableTable.
+func ReachableFields() []Column { return slices.Clone(reachableFields) }
+
+// StopFields returns the fields of StopTable.
+func StopFields() []Column { return slices.Clone(stopFields) }
+
+// StopoverFields returns the fields of StopoversTable.
+func StopoverFields() []Column { return slices.Clone(stopoverFields) }
+
+// JourneyFields returns the fields of JourneysTable.
+func JourneyFields() []Column { return slices.Clone(journeyFields) }
+
+// LegFields returns the fields of JourneyLegsTable and RefreshedJourneyTable.
+func LegFields() []Column { return slices.Clone(legFields) }
+
+// TripFields returns the fields of TripTable.
+func TripFields() []Column { return slices.Clone(tripFields) }
+
+// RadarFields returns the fields of RadarTable.
+func RadarFields() []Column { return slices.Clone(radarFields) }
+
+// FieldUsage describes a field for dbrest help fields.
+func FieldUsage(name string) string {
+	return fieldUsage[name]
+}
+
+// Select returns a table with only the named columns, in the given order.
+// Names match ignoring case and underscores, so plannedWhen selects
+// planned_when. Without names the default (non-extra) columns are kept.
+func (t Table) Select(names []string) (Table, error) {
+	if len(names) == 0 {
+		return t.visible(), nil
+	}
+	index := make(map[string]int, len(t.Columns))
+	for i, col := range t.Columns {
+		index[fieldKey(col.Name)] = i
+	}
+	picked := make([]int, 0, len(names))
+	for _, name := range names {
+		i, ok := index[fieldKey(name)]
+		if !ok {
+			available := make([]string, len(t.Columns))
+			for j, col := range t.Columns {
+				available[j] = col.Name
+			}
+			return Table{}, fmt.Errorf("%w %q (available: %s)", ErrUnknownField, name, strings.Join(available, ", "))
+		}
+		picked = append(picked, i)
+	}
+	return t.pick(picked), nil
+}
+
+// visible drops the extra columns.
+func (t Table) visible() Table {
+	var picked []int
+	for i, col := range t.Columns {
+		if !col.Extra {
+			picked = append(picked, i)
+		}
+	}
+	if len(picked) == len(t.Columns) {
+		return t
+	}
+	return t.pick(picked)
+}
+
+func (t Table) pick(indexes []int) Table {
+	out := Table{Columns: make([]Column, len(indexes)), Rows: make([]Row, len(t.Rows)), shape: t.shape}
+	for i, idx := range indexes {
+		out.Columns[i] = t.Columns[idx]
+		out.Columns[i].Extra = false
+	}
+	for r, row := range t.Rows {
+		cells := make([]string, len(indexes))
+		for i, idx := range indexes {
+			cells[i] = row.Cells[idx]
+		}
+		out.Rows[r] = Row{Cells: cells, Cancelled: row.Cancelled, Record: row.Record}
+	}
+	return out
+}
+
+// show makes an extra column part of the default columns.
+func (t *Table) show(name string) {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			t.Columns[i].Extra = false
+		}
+	}
+}
+
+func fieldKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", ""))
+}
@@ -0,0 +1,34 @@
+package format
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTableSelectFields(t *testing.T) {
+	data := []byte(`{"departures":[{"tripId":"1|2","when":"12:00","plannedWhen":"11:58","line":{"name":"S1","product":"suburban","operator":{"name":"S-Bahn Berlin"}},` +
+		`"direction":"Wannsee","delay":120,"remarks":[{"type":"hint","text":"Bicycles\nallowed"},{"type":"warning","summary":"Construction"}]}]}`)
+	table, err := StopoversTable(data)
+	if err != nil {
+		t.Fatalf("StopoversTable error: %v", err)
+	}
+
+	if out := table.Plain(false); out != "12:00\tS1\tWannsee\t-\t+2m\t-\n" {
+		t.Fatalf("extra fields leaked into default output: %q", out)
+	}
+
+	selected, err := table.Select([]string{"line", "plannedWhen", "trip_id", "product", "operator", "remarks", "delay"})
+	if err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	out := selected.Plain(true)
+	expected := "line\tplanned_when\ttrip_id\tproduct\toperator\tremarks\tdelay\n" +
+		"S1\t11:58\t1|2\tsuburban\tS-Bahn Berlin\tBicycles allowed; Construction\t+2m\n"
+	if out != expected {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	if _, err := table.Select([]string{"nope"}); !errors.Is(err, ErrUnknownField) {
+		t.Fatalf("expected ErrUnknownField, got %v", err)
+	}
+}
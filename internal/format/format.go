@@ -2,24 +2,77 @@ package format
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Location struct {
-	ID        string   `json:"id"`
-	Name      string   `json:"name"`
-	Type      string   `json:"type"`
-	Latitude  *float64 `json:"latitude"`
-	Longitude *float64 `json:"longitude"`
-	Distance  *int     `json:"distance"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Latitude  *float64        `json:"latitude"`
+	Longitude *float64        `json:"longitude"`
+	Distance  *int            `json:"distance"`
+	Products  map[string]bool `json:"products"`
+	// Position holds the coordinates of stops and stations; addresses and
+	// POIs carry them at the top level instead.
+	Position *Position `json:"location"`
+}
+
+// Coordinates returns the latitude and longitude from either place.
+func (l Location) Coordinates() (*float64, *float64) {
+	if l.Latitude == nil && l.Position != nil {
+		return l.Position.Latitude, l.Position.Longitude
+	}
+	return l.Latitude, l.Longitude
+}
+
+// Stop is a /stops/{id} response: a location with the lines serving it
+// and, where the API knows them, station metadata.
+type Stop struct {
+	Location
+	Lines            []Line         `json:"lines"`
+	Station          *Location      `json:"station"`
+	Facilities       map[string]any `json:"facilities"`
+	Ril100IDs        []string       `json:"ril100Ids"`
+	TransitAuthority string         `json:"transitAuthority"`
+}
+
+// ReachableStop is a stop from /stops/reachable-from with its travel time.
+type ReachableStop struct {
+	Location
+	// Duration is the travel time in minutes.
+	Duration int
+}
+
+type reachableGroup struct {
+	Duration int        `json:"duration"`
+	Stations []Location `json:"stations"`
 }
 
 type Line struct {
+	Name     string    `json:"name"`
+	Product  string    `json:"product"`
+	Operator *Operator `json:"operator"`
+}
+
+type Operator struct {
 	Name string `json:"name"`
 }
 
+type Remark struct {
+	Type    string `json:"type"`
+	Summary string `json:"summary"`
+	Text    string `json:"text"`
+}
+
 type Stopover struct {
+	TripID          string   `json:"tripId"`
 	When            string   `json:"when"`
 	PlannedWhen     string   `json:"plannedWhen"`
 	Delay           *int     `json:"delay"`
@@ -29,24 +82,50 @@ type Stopover struct {
 	Direction       string   `json:"direction"`
 	Line            Line     `json:"line"`
 	Stop            Location `json:"stop"`
+	Remarks         []Remark `json:"remarks"`
 }
 
 type JourneysResponse struct {
-	Journeys []Journey `json:"journeys"`
+	Journeys   []Journey `json:"journeys"`
+	EarlierRef string    `json:"earlierRef"`
+	LaterRef   string    `json:"laterRef"`
 }
 
 type Journey struct {
-	Legs      []Leg `json:"legs"`
-	Transfers int   `json:"transfers"`
+	Legs         []Leg  `json:"legs"`
+	Transfers    int    `json:"transfers"`
+	RefreshToken string `json:"refreshToken"`
 }
 
 type Leg struct {
-	Origin      *Location `json:"origin"`
-	Destination *Location `json:"destination"`
-	Departure   string    `json:"departure"`
-	PlannedDep  string    `json:"plannedDeparture"`
-	Arrival     string    `json:"arrival"`
-	PlannedArr  string    `json:"plannedArrival"`
+	TripID                   string    `json:"tripId"`
+	Direction                string    `json:"direction"`
+	Origin                   *Location `json:"origin"`
+	Destination              *Location `json:"destination"`
+	Departure                string    `json:"departure"`
+	PlannedDep               string    `json:"plannedDeparture"`
+	Arrival                  string    `json:"arrival"`
+	PlannedArr               string    `json:"plannedArrival"`
+	Line                     *Line     `json:"line"`
+	Walking                  bool      `json:"walking"`
+	Distance                 *int      `json:"distance"`
+	DeparturePlatform        string    `json:"departurePlatform"`
+	PlannedDeparturePlatform string    `json:"plannedDeparturePlatform"`
+	ArrivalPlatform          string    `json:"arrivalPlatform"`
+	PlannedArrivalPlatform   string    `json:"plannedArrivalPlatform"`
+	DepartureDelay           *int      `json:"departureDelay"`
+	ArrivalDelay             *int      `json:"arrivalDelay"`
+	Cancelled                bool      `json:"cancelled"`
+	Remarks                  []Remark  `json:"remarks"`
+	// token and index place the leg in its journey; set by legsTable.
+	token string
+	index int
+}
+
+type journeysPage struct {
+	Journeys   []json.RawMessage `json:"journeys"`
+	EarlierRef string            `json:"earlierRef,omitempty"`
+	LaterRef   string            `json:"laterRef,omitempty"`
 }
 
 type TripResponse struct {
@@ -56,6 +135,17 @@ type TripResponse struct {
 type Trip struct {
 	Line      Line       `json:"line"`
 	Stopovers []TripStop `json:"stopovers"`
+	Polyline  *Polyline  `json:"polyline"`
+}
+
+// Polyline is the FeatureCollection of points the API returns for
+// polyline=true.
+type Polyline struct {
+	Features []struct {
+		Geometry struct {
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
 }
 
 type TripStop struct {
@@ -66,6 +156,10 @@ type TripStop struct {
 	PlannedDeparture string   `json:"plannedDeparture"`
 	Platform         string   `json:"platform"`
 	PlannedPlatform  string   `json:"plannedPlatform"`
+	ArrivalDelay     *int     `json:"arrivalDelay"`
+	DepartureDelay   *int     `json:"departureDelay"`
+	// Line is the trip's line, copied to every stop by TripTable.
+	Line Line `json:"-"`
 }
 
 type RadarResponse struct {
@@ -73,6 +167,7 @@ type RadarResponse struct {
 }
 
 type Movement struct {
+	TripID    string   `json:"tripId"`
 	Line      Line     `json:"line"`
 	Direction string   `json:"direction"`
 	Location  Position `json:"location"`
@@ -89,51 +184,161 @@ type stopoversEnvelope struct {
 	Stopovers  []Stopover `json:"stopovers"`
 }
 
-// LocationsPlain formats /locations responses into line-based text.
-func LocationsPlain(data []byte, withHeader bool) (string, error) {
+// LocationsTable builds the table for /locations responses.
+func LocationsTable(data []byte) (Table, error) {
 	var locations []Location
 	if err := json.Unmarshal(data, &locations); err != nil {
-		return "", err
-	}
-	if len(locations) == 0 {
-		if withHeader {
-			return "no results\n", nil
-		}
-		return "", nil
+		return Table{}, err
 	}
-	var b strings.Builder
-	if withHeader {
-		b.WriteString("id\tname\ttype\tlatitude\tlongitude\tdistance_m\n")
+	return locationsTable(locations), nil
+}
+
+// NearbyTable builds the LocationsTable columns for /locations/nearby
+// responses, nearest first. Locations without a distance come last.
+func NearbyTable(data []byte) (Table, error) {
+	var locations []Location
+	if err := json.Unmarshal(data, &locations); err != nil {
+		return Table{}, err
 	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		a, b := locations[i].Distance, locations[j].Distance
+		return a != nil && (b == nil || *a < *b)
+	})
+	return locationsTable(locations), nil
+}
+
+func locationsTable(locations []Location) Table {
+	t := tableOf(locationFields)
 	for _, loc := range locations {
-		b.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\n",
+		lat, lon := loc.Coordinates()
+		t.add(loc, false,
 			loc.ID,
 			loc.Name,
 			loc.Type,
-			formatFloat(loc.Latitude),
-			formatFloat(loc.Longitude),
+			formatFloat(lat),
+			formatFloat(lon),
 			formatInt(loc.Distance),
-		))
+			formatProducts(loc.Products),
+		)
 	}
-	return b.String(), nil
+	return t
 }
 
-// StopoversPlain formats departures/arrivals into line-based text.
-func StopoversPlain(data []byte, withHeader bool) (string, error) {
-	stopovers, err := parseStopovers(data)
+// LocationsPlain formats /locations responses into line-based text.
+func LocationsPlain(data []byte, withHeader bool) (string, error) {
+	t, err := LocationsTable(data)
 	if err != nil {
 		return "", err
 	}
-	if len(stopovers) == 0 {
-		if withHeader {
-			return "no results\n", nil
+	return t.Plain(withHeader), nil
+}
+
+// StopTable builds the one-row table for /stops/{id} responses.
+func StopTable(data []byte) (Table, error) {
+	var stop Stop
+	if err := json.Unmarshal(data, &stop); err != nil {
+		return Table{}, err
+	}
+	var lines []string
+	for _, line := range stop.Lines {
+		if line.Name != "" && !slices.Contains(lines, line.Name) {
+			lines = append(lines, line.Name)
 		}
-		return "", nil
 	}
-	var b strings.Builder
-	if withHeader {
-		b.WriteString("time\tline\tdirection\tplatform\tdelay\tstatus\n")
+	stationID, stationName := "-", "-"
+	if stop.Station != nil {
+		stationID, stationName = pickString(stop.Station.ID, ""), pickString(stop.Station.Name, "")
+	}
+	lat, lon := stop.Coordinates()
+	t := tableOf(stopFields)
+	t.add(stop, false,
+		stop.ID,
+		stop.Name,
+		stop.Type,
+		formatFloat(lat),
+		formatFloat(lon),
+		formatProducts(stop.Products),
+		pickString(strings.Join(lines, ","), ""),
+		formatFacilities(stop.Facilities, false),
+		formatFacilities(stop.Facilities, true),
+		stationID,
+		stationName,
+		pickString(strings.Join(stop.Ril100IDs, ","), ""),
+		pickString(stop.TransitAuthority, ""),
+	)
+	return t, nil
+}
+
+// ReachableTable builds the table for /stops/reachable-from responses, one
+// row per stop, fastest first.
+func ReachableTable(data []byte) (Table, error) {
+	var groups []reachableGroup
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return Table{}, err
+	}
+	var stops []ReachableStop
+	for _, group := range groups {
+		for _, station := range group.Stations {
+			stops = append(stops, ReachableStop{Location: station, Duration: group.Duration})
+		}
+	}
+	sort.SliceStable(stops, func(i, j int) bool {
+		return stops[i].Duration < stops[j].Duration
+	})
+	t := tableOf(reachableFields)
+	for _, stop := range stops {
+		lat, lon := stop.Coordinates()
+		t.add(stop, false,
+			strconv.Itoa(stop.Duration),
+			stop.ID,
+			stop.Name,
+			formatFloat(lat),
+			formatFloat(lon),
+			formatProducts(stop.Products),
+		)
+	}
+	return t, nil
+}
+
+// TableGroup is a titled part of a table.
+type TableGroup struct {
+	Title string
+	Table Table
+}
+
+// ReachableGroups splits a ReachableTable into buckets of size minutes,
+// titled "up to 10 min", "10-20 min" and so on. Empty buckets are left out.
+func ReachableGroups(t Table, size int) []TableGroup {
+	if size <= 0 {
+		size = 10
+	}
+	var groups []TableGroup
+	for _, row := range t.Rows {
+		stop, ok := row.Record.(ReachableStop)
+		if !ok {
+			continue
+		}
+		bucket := max(stop.Duration-1, 0) / size
+		title := fmt.Sprintf("%d-%d min", bucket*size, (bucket+1)*size)
+		if bucket == 0 {
+			title = fmt.Sprintf("up to %d min", size)
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Title != title {
+			groups = append(groups, TableGroup{Title: title, Table: Table{Columns: t.Columns}})
+		}
+		last := &groups[len(groups)-1].Table
+		last.Rows = append(last.Rows, row)
+	}
+	return groups
+}
+
+// StopoversTable builds the table for departures/arrivals.
+func StopoversTable(data []byte) (Table, error) {
+	stopovers, err := parseStopovers(data)
+	if err != nil {
+		return Table{}, err
 	}
+	t := tableOf(stopoverFields)
 	for _, s := range stopovers {
 		timeValue := pickTime(s.When, s.PlannedWhen)
 		platform := pickString(s.Platform, s.PlannedPlatform)
@@ -141,33 +346,115 @@ func StopoversPlain(data []byte, withHeader bool) (string, error) {
 		if s.Cancelled {
 			status = "cancelled"
 		}
-		b.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\n",
+		t.add(s, s.Cancelled,
 			timeValue,
 			s.Line.Name,
 			s.Direction,
 			platform,
 			formatDelay(s.Delay),
 			status,
-		))
-	}
-	return b.String(), nil
+			pickTime(s.PlannedWhen, ""),
+			pickString(s.PlannedPlatform, ""),
+			pickString(s.TripID, ""),
+			pickString(s.Line.Product, ""),
+			operatorName(s.Line.Operator),
+			formatRemarks(s.Remarks),
+			pickString(s.Stop.Name, ""),
+			pickString(s.Stop.ID, ""),
+		)
+	}
+	return t, nil
 }
 
-// JourneysPlain formats /journeys responses into line-based text.
-func JourneysPlain(data []byte, withHeader bool) (string, error) {
-	var resp JourneysResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
+// StopoversPlain formats departures/arrivals into line-based text.
+func StopoversPlain(data []byte, withHeader bool) (string, error) {
+	t, err := StopoversTable(data)
+	if err != nil {
 		return "", err
 	}
-	if len(resp.Journeys) == 0 {
-		if withHeader {
-			return "no results\n", nil
+	return t.Plain(withHeader), nil
+}
+
+// MergeStopovers merges departure or arrival boards of several stops into
+// one JSON array, sorted by real-time (else planned) time. A trip serving
+// more than one of the stops is kept only at its earliest stop. A positive
+// limit keeps only the first limit stopovers.
+func MergeStopovers(boards [][]byte, limit int) ([]byte, error) {
+	type entry struct {
+		raw json.RawMessage
+		at  time.Time
+		ok  bool
+		id  string
+	}
+	var entries []entry
+	for _, data := range boards {
+		raws, err := rawStopovers(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range raws {
+			var s Stopover
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, err
+			}
+			at, err := time.Parse(time.RFC3339, pickTime(s.When, s.PlannedWhen))
+			entries = append(entries, entry{raw: raw, at: at, ok: err == nil, id: s.TripID})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.ok != b.ok {
+			return a.ok
+		}
+		return a.at.Before(b.at)
+	})
+	merged := make([]json.RawMessage, 0, len(entries))
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.id != "" {
+			if seen[e.id] {
+				continue
+			}
+			seen[e.id] = true
+		}
+		merged = append(merged, e.raw)
+		if len(merged) == limit {
+			break
 		}
-		return "", nil
 	}
-	var b strings.Builder
-	if withHeader {
-		b.WriteString("departure\torigin\tarrival\tdestination\ttransfers\n")
+	return json.Marshal(merged)
+}
+
+// MergedStopoversTable is StopoversTable for a MergeStopovers board, with
+// the stop column shown.
+func MergedStopoversTable(data []byte) (Table, error) {
+	t, err := StopoversTable(data)
+	if err != nil {
+		return Table{}, err
+	}
+	t.show("stop")
+	return t, nil
+}
+
+// JourneyOptions selects optional journey columns.
+type JourneyOptions struct {
+	// Tokens shows the refresh_token column by default.
+	Tokens bool
+}
+
+type journeyResponse struct {
+	Journey Journey `json:"journey"`
+}
+
+// JourneysTable builds the table for /journeys responses.
+func JourneysTable(data []byte, opts JourneyOptions) (Table, error) {
+	var resp JourneysResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return Table{}, err
+	}
+	t := tableOf(journeyFields)
+	if opts.Tokens {
+		t.show("refresh_token")
 	}
 	for _, journey := range resp.Journeys {
 		if len(journey.Legs) == 0 {
@@ -179,73 +466,259 @@ func JourneysPlain(data []byte, withHeader bool) (string, error) {
 		destination := locationName(last.Destination)
 		departure := pickTime(first.Departure, first.PlannedDep)
 		arrival := pickTime(last.Arrival, last.PlannedArr)
-		b.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%d\n",
+		var lines []string
+		for _, leg := range journey.Legs {
+			if !leg.Walking && leg.Line != nil && leg.Line.Name != "" {
+				lines = append(lines, leg.Line.Name)
+			}
+		}
+		t.add(journey, false,
 			departure,
 			origin,
 			arrival,
 			destination,
-			journey.Transfers,
-		))
+			fmt.Sprintf("%d", journey.Transfers),
+			pickString(journey.RefreshToken, ""),
+			pickTime(first.PlannedDep, ""),
+			pickTime(last.PlannedArr, ""),
+			strings.TrimSuffix(formatGap(departure, arrival), "m"),
+			pickString(strings.Join(lines, ","), ""),
+		)
+	}
+	return t, nil
+}
+
+// JourneysPlain formats /journeys responses into line-based text.
+func JourneysPlain(data []byte, withHeader bool) (string, error) {
+	t, err := JourneysTable(data, JourneyOptions{})
+	if err != nil {
+		return "", err
 	}
-	return b.String(), nil
+	return t.Plain(withHeader), nil
 }
 
-// TripPlain formats /trips/{id} responses into line-based text.
-func TripPlain(data []byte, withHeader bool) (string, error) {
-	var resp TripResponse
+// JourneyLegsTable builds a table with one row per leg of every journey.
+// The transfer column holds the wait since the previous leg arrived.
+func JourneyLegsTable(data []byte, opts JourneyOptions) (Table, error) {
+	var resp JourneysResponse
 	if err := json.Unmarshal(data, &resp); err != nil {
-		return "", err
+		return Table{}, err
+	}
+	return legsTable(resp.Journeys, opts), nil
+}
+
+// RefreshedJourneyTable builds the per-leg table for /journeys/{ref} responses.
+func RefreshedJourneyTable(data []byte, opts JourneyOptions) (Table, error) {
+	var resp journeyResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return Table{}, err
 	}
-	if len(resp.Trip.Stopovers) == 0 {
-		if withHeader {
-			return "no results\n", nil
+	if len(resp.Journey.Legs) == 0 {
+		return legsTable(nil, opts), nil
+	}
+	return legsTable([]Journey{resp.Journey}, opts), nil
+}
+
+func legsTable(journeys []Journey, opts JourneyOptions) Table {
+	t := tableOf(legFields)
+	if opts.Tokens {
+		t.show("refresh_token")
+	}
+	for i, journey := range journeys {
+		for j, leg := range journey.Legs {
+			transfer := "-"
+			if j > 0 {
+				prev := journey.Legs[j-1]
+				transfer = formatGap(pickTime(prev.Arrival, prev.PlannedArr), pickTime(leg.Departure, leg.PlannedDep))
+			}
+			status := "-"
+			if leg.Cancelled {
+				status = "cancelled"
+			}
+			product, operator := "-", "-"
+			if leg.Line != nil {
+				product = pickString(leg.Line.Product, "")
+				operator = operatorName(leg.Line.Operator)
+			}
+			cells := []string{
+				fmt.Sprintf("%d", i+1),
+				legLine(leg),
+				locationName(leg.Origin),
+				pickString(leg.DeparturePlatform, leg.PlannedDeparturePlatform),
+				pickTime(leg.PlannedDep, ""),
+				pickTime(leg.Departure, leg.PlannedDep),
+				formatDelay(leg.DepartureDelay),
+				locationName(leg.Destination),
+				pickString(leg.ArrivalPlatform, leg.PlannedArrivalPlatform),
+				pickTime(leg.PlannedArr, ""),
+				pickTime(leg.Arrival, leg.PlannedArr),
+				formatDelay(leg.ArrivalDelay),
+				transfer,
+				status,
+				pickString(journey.RefreshToken, ""),
+				pickString(leg.TripID, ""),
+				product,
+				operator,
+				pickString(leg.Direction, ""),
+				formatRemarks(leg.Remarks),
+				pickString(leg.PlannedDeparturePlatform, ""),
+				pickString(leg.PlannedArrivalPlatform, ""),
+			}
+			leg.token, leg.index = journey.RefreshToken, j
+			t.add(leg, leg.Cancelled, cells...)
+		}
+	}
+	return t
+}
+
+// JourneyRefs returns the earlierRef and laterRef of a /journeys response.
+func JourneyRefs(data []byte) (string, string, error) {
+	var resp JourneysResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", "", err
+	}
+	return resp.EarlierRef, resp.LaterRef, nil
+}
+
+// MergeJourneys combines consecutive /journeys pages into one response,
+// dropping journeys already seen. The result keeps the earlierRef of the
+// first page and the laterRef of the last one.
+func MergeJourneys(pages [][]byte) ([]byte, error) {
+	var merged journeysPage
+	seen := map[string]bool{}
+	for i, data := range pages {
+		var page journeysPage
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			merged.EarlierRef = page.EarlierRef
+		}
+		merged.LaterRef = page.LaterRef
+		for _, raw := range page.Journeys {
+			var journey Journey
+			if err := json.Unmarshal(raw, &journey); err != nil {
+				return nil, err
+			}
+			key := journeyKey(journey)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged.Journeys = append(merged.Journeys, raw)
 		}
-		return "", nil
+	}
+	if merged.Journeys == nil {
+		merged.Journeys = []json.RawMessage{}
+	}
+	return json.Marshal(merged)
+}
+
+// journeyKey identifies a journey across pages: the refresh token when
+// present, otherwise the planned departure and line of every leg.
+func journeyKey(journey Journey) string {
+	if journey.RefreshToken != "" {
+		return journey.RefreshToken
 	}
 	var b strings.Builder
-	if withHeader {
-		b.WriteString("line\tstop\tarrival\tdeparture\tplatform\n")
+	for _, leg := range journey.Legs {
+		b.WriteString(leg.PlannedDep)
+		b.WriteString("|")
+		b.WriteString(legLine(leg))
+		b.WriteString("|")
+		b.WriteString(locationName(leg.Origin))
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+// TripTable builds the table for /trips/{id} responses.
+func TripTable(data []byte) (Table, error) {
+	var resp TripResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return Table{}, err
 	}
+	t := tableOf(tripFields)
 	for _, stop := range resp.Trip.Stopovers {
+		stop.Line = resp.Trip.Line
 		arrival := pickTime(stop.Arrival, stop.PlannedArrival)
 		departure := pickTime(stop.Departure, stop.PlannedDeparture)
 		platform := pickString(stop.Platform, stop.PlannedPlatform)
-		b.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n",
+		t.add(stop, false,
 			resp.Trip.Line.Name,
 			stop.Stop.Name,
 			arrival,
 			departure,
 			platform,
-		))
-	}
-	return b.String(), nil
+			pickString(stop.Stop.ID, ""),
+			pickTime(stop.PlannedArrival, ""),
+			pickTime(stop.PlannedDeparture, ""),
+			pickString(stop.PlannedPlatform, ""),
+			formatDelay(stop.ArrivalDelay),
+			formatDelay(stop.DepartureDelay),
+			pickString(resp.Trip.Line.Product, ""),
+			operatorName(resp.Trip.Line.Operator),
+		)
+	}
+	t.shape = tripShape(resp.Trip)
+	return t, nil
 }
 
-// RadarPlain formats /radar responses into line-based text.
-func RadarPlain(data []byte, withHeader bool) (string, error) {
-	var resp RadarResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return "", err
+// RemainingTripTable is TripTable from the stop with id stopID onwards,
+// with the delay columns shown. Without such a stop it keeps every stop.
+func RemainingTripTable(data []byte, stopID string) (Table, error) {
+	t, err := TripTable(data)
+	if err != nil {
+		return Table{}, err
 	}
-	if len(resp.Movements) == 0 {
-		if withHeader {
-			return "no results\n", nil
+	for i, row := range t.Rows {
+		if stop, ok := row.Record.(TripStop); ok && stopID != "" && stop.Stop.ID == stopID {
+			t.Rows = t.Rows[i:]
+			break
 		}
-		return "", nil
 	}
-	var b strings.Builder
-	if withHeader {
-		b.WriteString("line\tdirection\tlatitude\tlongitude\n")
+	t.show("arrival_delay")
+	t.show("departure_delay")
+	return t, nil
+}
+
+// TripPlain formats /trips/{id} responses into line-based text.
+func TripPlain(data []byte, withHeader bool) (string, error) {
+	t, err := TripTable(data)
+	if err != nil {
+		return "", err
 	}
+	return t.Plain(withHeader), nil
+}
+
+// RadarTable builds the table for /radar responses.
+func RadarTable(data []byte) (Table, error) {
+	var resp RadarResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return Table{}, err
+	}
+	t := tableOf(radarFields)
 	for _, movement := range resp.Movements {
-		b.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\n",
+		t.add(movement, false,
 			movement.Line.Name,
 			movement.Direction,
 			formatFloat(movement.Location.Latitude),
 			formatFloat(movement.Location.Longitude),
-		))
+			pickString(movement.TripID, ""),
+			pickString(movement.Line.Product, ""),
+			operatorName(movement.Line.Operator),
+		)
+	}
+	return t, nil
+}
+
+// RadarPlain formats /radar responses into line-based text.
+func RadarPlain(data []byte, withHeader bool) (string, error) {
+	t, err := RadarTable(data)
+	if err != nil {
+		return "", err
 	}
-	return b.String(), nil
+	return t.Plain(withHeader), nil
 }
 
 func formatFloat(value *float64) string {
@@ -262,6 +735,81 @@ func formatInt(value *int) string {
 	return fmt.Sprintf("%d", *value)
 }
 
+// formatGap returns the whole minutes between two RFC 3339 times.
+func formatGap(from, to string) string {
+	start, errStart := time.Parse(time.RFC3339, from)
+	end, errEnd := time.Parse(time.RFC3339, to)
+	if errStart != nil || errEnd != nil {
+		return "-"
+	}
+	return fmt.Sprintf("%dm", int(end.Sub(start).Minutes()))
+}
+
+// formatProducts lists the enabled products, sorted.
+func formatProducts(products map[string]bool) string {
+	var enabled []string
+	for product, ok := range products {
+		if ok {
+			enabled = append(enabled, product)
+		}
+	}
+	sort.Strings(enabled)
+	return pickString(strings.Join(enabled, ","), "")
+}
+
+// formatRemarks joins the remark texts with "; ", flattening whitespace so
+// a remark never breaks a line.
+func formatRemarks(remarks []Remark) string {
+	var texts []string
+	for _, remark := range remarks {
+		text := strings.Join(strings.Fields(pickString(remark.Text, remark.Summary)), " ")
+		if text != "-" && !slices.Contains(texts, text) {
+			texts = append(texts, text)
+		}
+	}
+	return pickString(strings.Join(texts, "; "), "")
+}
+
+// accessibilityFacilities are the facility key parts that describe
+// accessibility rather than amenities.
+var accessibilityFacilities = []string{"stepfree", "stepless", "boardingaid", "mobility", "wheelchair", "accessib"}
+
+// formatFacilities lists facilities as sorted key=value pairs, either the
+// accessibility ones or all others.
+func formatFacilities(facilities map[string]any, accessibility bool) string {
+	var pairs []string
+	for key, value := range facilities {
+		lower := strings.ToLower(key)
+		isAccess := slices.ContainsFunc(accessibilityFacilities, func(part string) bool {
+			return strings.Contains(lower, part)
+		})
+		if isAccess != accessibility {
+			continue
+		}
+		var text string
+		switch v := value.(type) {
+		case string, bool, float64:
+			text = fmt.Sprint(v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			text = string(data)
+		}
+		pairs = append(pairs, key+"="+strings.Join(strings.Fields(text), " "))
+	}
+	sort.Strings(pairs)
+	return pickString(strings.Join(pairs, ","), "")
+}
+
+func operatorName(operator *Operator) string {
+	if operator == nil {
+		return "-"
+	}
+	return pickString(operator.Name, "")
+}
+
 func formatDelay(delay *int) string {
 	if delay == nil {
 		return "-"
@@ -295,6 +843,19 @@ func pickTime(primary, fallback string) string {
 	return "-"
 }
 
+func legLine(leg Leg) string {
+	if leg.Walking {
+		if leg.Distance != nil {
+			return fmt.Sprintf("walk %dm", *leg.Distance)
+		}
+		return "walk"
+	}
+	if leg.Line == nil || strings.TrimSpace(leg.Line.Name) == "" {
+		return "-"
+	}
+	return leg.Line.Name
+}
+
 func locationName(loc *Location) string {
 	if loc == nil {
 		return "-"
@@ -305,6 +866,57 @@ func locationName(loc *Location) string {
 	return loc.ID
 }
 
+// RawItems returns the row items of a response as raw JSON: the top-level
+// array, or the array inside a departures/arrivals/stopovers/movements/
+// journeys envelope or a trip.
+func RawItems(data []byte) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err == nil {
+		return items, nil
+	}
+	var env map[string]json.RawMessage
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if trip, ok := env["trip"]; ok {
+		return RawItems(trip)
+	}
+	for _, key := range []string{"departures", "arrivals", "stopovers", "movements", "journeys"} {
+		if raw, ok := env[key]; ok {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, err
+			}
+			return items, nil
+		}
+	}
+	return nil, errors.New("response has no list of items")
+}
+
+// rawStopovers is parseStopovers keeping each stopover as raw JSON.
+func rawStopovers(data []byte) ([]json.RawMessage, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err == nil {
+		return raws, nil
+	} else {
+		var env struct {
+			Departures []json.RawMessage `json:"departures"`
+			Arrivals   []json.RawMessage `json:"arrivals"`
+			Stopovers  []json.RawMessage `json:"stopovers"`
+		}
+		if errEnv := json.Unmarshal(data, &env); errEnv == nil {
+			switch {
+			case env.Departures != nil:
+				return env.Departures, nil
+			case env.Arrivals != nil:
+				return env.Arrivals, nil
+			case env.Stopovers != nil:
+				return env.Stopovers, nil
+			}
+		}
+		return nil, err
+	}
+}
+
 func parseStopovers(data []byte) ([]Stopover, error) {
 	var stopovers []Stopover
 	if err := json.Unmarshal(data, &stopovers); err == nil {
@@ -1,6 +1,10 @@
 package format
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func TestLocationsPlain(t *testing.T) {
 	data := []byte(`[{"id":"123","name":"Berlin Hbf","type":"station","latitude":52.525,"longitude":13.369,"distance":120}]`)
@@ -40,3 +44,134 @@ func TestStopoversPlainEnvelope(t *testing.T) {
 		t.Fatalf("unexpected output:\n%s", out)
 	}
 }
+
+func TestJourneyLegsTable(t *testing.T) {
+	data := []byte(`{"journeys":[{"legs":[` +
+		`{"origin":{"name":"Berlin Hbf"},"destination":{"name":"Hamburg Hbf"},"plannedDeparture":"2024-01-01T08:00:00+01:00","departure":"2024-01-01T08:02:00+01:00","departureDelay":120,"plannedArrival":"2024-01-01T09:45:00+01:00","arrival":"2024-01-01T09:45:00+01:00","arrivalDelay":0,"departurePlatform":"7","plannedArrivalPlatform":"14","line":{"name":"ICE 1000"}},` +
+		`{"origin":{"name":"Hamburg Hbf"},"destination":{"name":"Dammtor"},"plannedDeparture":"2024-01-01T09:53:00+01:00","plannedArrival":"2024-01-01T10:05:00+01:00","walking":true,"distance":850}` +
+		`]}]}`)
+	table, err := JourneyLegsTable(data, JourneyOptions{})
+	if err != nil {
+		t.Fatalf("JourneyLegsTable error: %v", err)
+	}
+	expected := "1\tICE 1000\tBerlin Hbf\t7\t2024-01-01T08:00:00+01:00\t2024-01-01T08:02:00+01:00\t+2m\tHamburg Hbf\t14\t2024-01-01T09:45:00+01:00\t2024-01-01T09:45:00+01:00\t0m\t-\t-\n" +
+		"1\twalk 850m\tHamburg Hbf\t-\t2024-01-01T09:53:00+01:00\t2024-01-01T09:53:00+01:00\t-\tDammtor\t-\t2024-01-01T10:05:00+01:00\t2024-01-01T10:05:00+01:00\t-\t8m\t-\n"
+	if got := table.Plain(false); got != expected {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+}
+
+func TestMergeJourneysDeduplicates(t *testing.T) {
+	first := []byte(`{"journeys":[{"legs":[{"plannedDeparture":"2024-01-01T08:00:00+01:00","line":{"name":"ICE 1"}}]}],"earlierRef":"e1","laterRef":"l1"}`)
+	second := []byte(`{"journeys":[{"legs":[{"plannedDeparture":"2024-01-01T08:00:00+01:00","line":{"name":"ICE 1"}}]},{"legs":[{"plannedDeparture":"2024-01-01T09:00:00+01:00","line":{"name":"ICE 3"}}]}],"earlierRef":"e2","laterRef":"l2"}`)
+	merged, err := MergeJourneys([][]byte{first, second})
+	if err != nil {
+		t.Fatalf("MergeJourneys error: %v", err)
+	}
+	expected := `{"journeys":[{"legs":[{"plannedDeparture":"2024-01-01T08:00:00+01:00","line":{"name":"ICE 1"}}]},{"legs":[{"plannedDeparture":"2024-01-01T09:00:00+01:00","line":{"name":"ICE 3"}}]}],"earlierRef":"e1","laterRef":"l2"}`
+	if string(merged) != expected {
+		t.Fatalf("unexpected merge:\n%s", merged)
+	}
+}
+
+func TestStopTable(t *testing.T) {
+	data := []byte(`{"type":"stop","id":"8011160","name":"Berlin Hbf","location":{"latitude":52.525,"longitude":13.369},` +
+		`"products":{"nationalExpress":true,"bus":false,"suburban":true},` +
+		`"lines":[{"name":"ICE 1"},{"name":"S5"},{"name":"ICE 1"}],` +
+		`"station":{"id":"8011160","name":"Berlin Hbf"},` +
+		`"facilities":{"toilets":"yes","stepFreeAccess":"yes","boardingAid":"yes","parkingLots":"no"},` +
+		`"ril100Ids":["BL"]}`)
+	table, err := StopTable(data)
+	if err != nil {
+		t.Fatalf("StopTable error: %v", err)
+	}
+	expected := "8011160\tBerlin Hbf\tstop\t52.525000\t13.369000\tnationalExpress,suburban\tICE 1,S5\t" +
+		"parkingLots=no,toilets=yes\tboardingAid=yes,stepFreeAccess=yes\n"
+	if out := table.Plain(false); out != expected {
+		t.Fatalf("unexpected output:\n%q", out)
+	}
+	table, err = table.Select([]string{"station_name", "ril100", "transit_authority"})
+	if err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if out := table.Plain(false); out != "Berlin Hbf\tBL\t-\n" {
+		t.Fatalf("unexpected extra fields: %q", out)
+	}
+}
+
+func TestNearbyTableSortsByDistance(t *testing.T) {
+	data := []byte(`[{"id":"1","name":"Far","distance":400},{"id":"2","name":"Unknown"},{"id":"3","name":"Near","distance":90}]`)
+	table, err := NearbyTable(data)
+	if err != nil {
+		t.Fatalf("NearbyTable error: %v", err)
+	}
+	table, err = table.Select([]string{"name", "distance_m"})
+	if err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if out := table.Plain(false); out != "Near\t90\nFar\t400\nUnknown\t-\n" {
+		t.Fatalf("unexpected output:\n%q", out)
+	}
+}
+
+func TestReachableGroups(t *testing.T) {
+	data := []byte(`[{"duration":4,"stations":[{"id":"1"},{"id":"2"}]},{"duration":10,"stations":[{"id":"3"}]},{"duration":25,"stations":[{"id":"4"}]}]`)
+	table, err := ReachableTable(data)
+	if err != nil {
+		t.Fatalf("ReachableTable error: %v", err)
+	}
+	groups := ReachableGroups(table, 10)
+	var got []string
+	for _, group := range groups {
+		got = append(got, fmt.Sprintf("%s:%d", group.Title, len(group.Table.Rows)))
+	}
+	if strings.Join(got, ",") != "up to 10 min:3,20-30 min:1" {
+		t.Fatalf("unexpected groups: %v", got)
+	}
+}
+
+func TestMergeStopovers(t *testing.T) {
+	tram := []byte(`[{"tripId":"t1","when":"2024-01-01T12:05:00+01:00","direction":"Hackescher Markt","line":{"name":"M4"},"stop":{"id":"1","name":"Tram"}},` +
+		`{"tripId":"shared","when":"2024-01-01T12:09:00+01:00","direction":"Zoo","line":{"name":"200"},"stop":{"id":"1","name":"Tram"}}]`)
+	sbahn := []byte(`{"departures":[{"tripId":"s1","when":null,"plannedWhen":"2024-01-01T12:01:00+01:00","direction":"Spandau","line":{"name":"S5"},"stop":{"id":"2","name":"S-Bahn"}},` +
+		`{"tripId":"shared","when":"2024-01-01T12:07:00+01:00","direction":"Zoo","line":{"name":"200"},"stop":{"id":"2","name":"S-Bahn"}}]}`)
+
+	merged, err := MergeStopovers([][]byte{tram, sbahn}, 0)
+	if err != nil {
+		t.Fatalf("MergeStopovers error: %v", err)
+	}
+	table, err := MergedStopoversTable(merged)
+	if err != nil {
+		t.Fatalf("MergedStopoversTable error: %v", err)
+	}
+	expected := "2024-01-01T12:01:00+01:00\tS5\tSpandau\t-\t-\t-\tS-Bahn\n" +
+		"2024-01-01T12:05:00+01:00\tM4\tHackescher Markt\t-\t-\t-\tTram\n" +
+		"2024-01-01T12:07:00+01:00\t200\tZoo\t-\t-\t-\tS-Bahn\n"
+	if out := table.Plain(false); out != expected {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestRemainingTripTable(t *testing.T) {
+	data := []byte(`{"trip":{"line":{"name":"S1"},"stopovers":[` +
+		`{"stop":{"id":"1","name":"Wannsee"},"departure":"2024-01-01T12:00:00+01:00","departureDelay":0},` +
+		`{"stop":{"id":"2","name":"Zoo"},"arrival":"2024-01-01T12:20:00+01:00","arrivalDelay":120,"departure":"2024-01-01T12:21:00+01:00","departureDelay":120},` +
+		`{"stop":{"id":"3","name":"Oranienburg"},"arrival":"2024-01-01T13:00:00+01:00","arrivalDelay":60}]}}`)
+	table, err := RemainingTripTable(data, "2")
+	if err != nil {
+		t.Fatalf("RemainingTripTable error: %v", err)
+	}
+	expected := "S1\tZoo\t2024-01-01T12:20:00+01:00\t2024-01-01T12:21:00+01:00\t-\t+2m\t+2m\n" +
+		"S1\tOranienburg\t2024-01-01T13:00:00+01:00\t-\t-\t+1m\t-\n"
+	if out := table.Plain(false); out != expected {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	table, err = RemainingTripTable(data, "9")
+	if err != nil {
+		t.Fatalf("RemainingTripTable error: %v", err)
+	}
+	if len(table.Rows) != 3 {
+		t.Fatalf("expected every stop for an unknown stop id, got %d", len(table.Rows))
+	}
+}
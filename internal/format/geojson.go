@@ -0,0 +1,143 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// shape is a LineString feature with its own properties.
+type shape struct {
+	name   string
+	points [][2]float64
+}
+
+// GeoJSON renders a FeatureCollection with a Point for every row whose
+// record has a position (locations, stops, trip stops and radar
+// movements), with the row's columns as properties. A trip fetched with
+// polyline=true adds a LineString of its route. Reachable stops get a
+// marker-color from green (fastest) to red (slowest). Rows without a
+// position are left out.
+func (t Table) GeoJSON() string {
+	t = t.visible()
+	var b strings.Builder
+	b.WriteString(`{"type":"FeatureCollection","features":[`)
+	first := true
+	next := func() {
+		if !first {
+			b.WriteString(",")
+		}
+		first = false
+	}
+	if t.shape != nil && len(t.shape.points) > 1 {
+		next()
+		b.WriteString(`{"type":"Feature","geometry":{"type":"LineString","coordinates":[`)
+		for i, point := range t.shape.points {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			writeCoordinates(&b, point[0], point[1])
+		}
+		b.WriteString(`]},"properties":{"line":`)
+		b.Write(jsonString(t.shape.name))
+		b.WriteString("}}")
+	}
+	slowest := 0
+	for _, row := range t.Rows {
+		if stop, ok := row.Record.(ReachableStop); ok {
+			slowest = max(slowest, stop.Duration)
+		}
+	}
+	for _, row := range t.Rows {
+		lon, lat, ok := recordPosition(row.Record)
+		if !ok {
+			continue
+		}
+		next()
+		b.WriteString(`{"type":"Feature","geometry":{"type":"Point","coordinates":`)
+		writeCoordinates(&b, lon, lat)
+		b.WriteString(`},"properties":`)
+		var props strings.Builder
+		writeJSONRow(&props, t.Columns, row)
+		properties := props.String()
+		if stop, ok := row.Record.(ReachableStop); ok {
+			properties = strings.TrimSuffix(properties, "}")
+			if properties != "{" {
+				properties += ","
+			}
+			properties += `"marker-color":"` + durationColor(stop.Duration, slowest) + `"}`
+		}
+		b.WriteString(properties)
+		b.WriteString("}")
+	}
+	b.WriteString("]}\n")
+	return b.String()
+}
+
+// recordPosition returns the longitude and latitude of a row record.
+func recordPosition(record any) (float64, float64, bool) {
+	switch r := record.(type) {
+	case Location:
+		lat, lon := r.Coordinates()
+		return position(lon, lat)
+	case Stop:
+		lat, lon := r.Coordinates()
+		return position(lon, lat)
+	case ReachableStop:
+		lat, lon := r.Coordinates()
+		return position(lon, lat)
+	case TripStop:
+		lat, lon := r.Stop.Coordinates()
+		return position(lon, lat)
+	case Movement:
+		return position(r.Location.Longitude, r.Location.Latitude)
+	}
+	return 0, 0, false
+}
+
+func position(lon, lat *float64) (float64, float64, bool) {
+	if lon == nil || lat == nil {
+		return 0, 0, false
+	}
+	return *lon, *lat, true
+}
+
+// durationScale runs from green over yellow to red.
+var durationScale = [][3]float64{{0x1a, 0x98, 0x50}, {0xfe, 0xe0, 0x8b}, {0xd7, 0x30, 0x27}}
+
+// durationColor returns the hex colour of minutes on a scale up to slowest.
+func durationColor(minutes, slowest int) string {
+	ratio := 0.0
+	if slowest > 0 {
+		ratio = min(max(float64(minutes)/float64(slowest), 0), 1)
+	}
+	segment := min(int(ratio*2), 1)
+	from, to := durationScale[segment], durationScale[segment+1]
+	local := ratio*2 - float64(segment)
+	var rgb [3]int
+	for i := range rgb {
+		rgb[i] = int(from[i] + (to[i]-from[i])*local + 0.5)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+}
+
+func tripShape(trip Trip) *shape {
+	if trip.Polyline == nil {
+		return nil
+	}
+	s := &shape{name: trip.Line.Name}
+	for _, feature := range trip.Polyline.Features {
+		if coords := feature.Geometry.Coordinates; len(coords) >= 2 {
+			s.points = append(s.points, [2]float64{coords[0], coords[1]})
+		}
+	}
+	return s
+}
+
+func writeCoordinates(b *strings.Builder, lon, lat float64) {
+	b.WriteString("[")
+	b.WriteString(strconv.FormatFloat(lon, 'f', -1, 64))
+	b.WriteString(",")
+	b.WriteString(strconv.FormatFloat(lat, 'f', -1, 64))
+	b.WriteString("]")
+}
@@ -0,0 +1,61 @@
+package format
+
+import "testing"
+
+func TestTableGeoJSONTrip(t *testing.T) {
+	data := []byte(`{"trip":{"line":{"name":"S1"},"stopovers":[` +
+		`{"stop":{"name":"A","location":{"latitude":52.5,"longitude":13.4}}},` +
+		`{"stop":{"name":"B"}}],` +
+		`"polyline":{"type":"FeatureCollection","features":[` +
+		`{"geometry":{"type":"Point","coordinates":[13.4,52.5]}},` +
+		`{"geometry":{"type":"Point","coordinates":[13.41,52.51]}}]}}}`)
+
+	table, err := TripTable(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table, err = table.Select([]string{"line", "stop"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[13.4,52.5],[13.41,52.51]]},"properties":{"line":"S1"}},` +
+		`{"type":"Feature","geometry":{"type":"Point","coordinates":[13.4,52.5]},"properties":{"line":"S1","stop":"A"}}]}` + "\n"
+	if got := table.GeoJSON(); got != expected {
+		t.Fatalf("unexpected geojson:\n%s", got)
+	}
+}
+
+func TestTableGeoJSONEmpty(t *testing.T) {
+	table, err := RadarTable([]byte(`{"movements":[]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := table.GeoJSON(); got != `{"type":"FeatureCollection","features":[]}`+"\n" {
+		t.Fatalf("unexpected geojson: %q", got)
+	}
+}
+
+func TestTableGeoJSONReachable(t *testing.T) {
+	data := []byte(`[{"duration":20,"stations":[{"id":"2","name":"B","location":{"latitude":52.6,"longitude":13.5}}]},` +
+		`{"duration":0,"stations":[{"id":"1","name":"A","location":{"latitude":52.5,"longitude":13.4}}]}]`)
+	table, err := ReachableTable(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table, err = table.Select([]string{"name", "duration"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","geometry":{"type":"Point","coordinates":[13.4,52.5]},"properties":{"name":"A","duration":0,"marker-color":"#1a9850"}},` +
+		`{"type":"Feature","geometry":{"type":"Point","coordinates":[13.5,52.6]},"properties":{"name":"B","duration":20,"marker-color":"#d73027"}}]}` + "\n"
+	if got := table.GeoJSON(); got != expected {
+		t.Fatalf("unexpected geojson:\n%s", got)
+	}
+	if got := durationColor(10, 20); got != "#fee08b" {
+		t.Fatalf("unexpected midpoint colour %s", got)
+	}
+}
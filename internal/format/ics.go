@@ -0,0 +1,195 @@
+package format
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	// The calendar times must be right on systems without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+const (
+	icsTimeZone = "Europe/Berlin"
+	icsLayout   = "20060102T150405"
+	icsLineMax  = 75
+)
+
+// icsBerlin describes Europe/Berlin for calendars that do not know it.
+var icsBerlin = []string{
+	"BEGIN:VTIMEZONE",
+	"TZID:Europe/Berlin",
+	"BEGIN:DAYLIGHT",
+	"TZOFFSETFROM:+0100",
+	"TZOFFSETTO:+0200",
+	"TZNAME:CEST",
+	"DTSTART:19700329T020000",
+	"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+	"END:DAYLIGHT",
+	"BEGIN:STANDARD",
+	"TZOFFSETFROM:+0200",
+	"TZOFFSETTO:+0100",
+	"TZNAME:CET",
+	"DTSTART:19701025T030000",
+	"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+	"END:STANDARD",
+	"END:VTIMEZONE",
+}
+
+// ICS renders an iCalendar VCALENDAR with one VEVENT per journey or leg
+// row. Times are written in Europe/Berlin; the UID is derived from the
+// journey's refresh token, so exporting the same journey again updates the
+// event instead of adding one. Walking legs and rows without times are
+// left out. now is the DTSTAMP of every event.
+func (t Table) ICS(now time.Time) (string, error) {
+	berlin, err := time.LoadLocation(icsTimeZone)
+	if err != nil {
+		return "", err
+	}
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//dbrest//journeys//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	lines = append(lines, icsBerlin...)
+	for _, row := range t.Rows {
+		var legs []Leg
+		uid := ""
+		switch r := row.Record.(type) {
+		case Journey:
+			legs = r.Legs
+			uid = eventUID(r.RefreshToken, r.Legs, 0)
+		case Leg:
+			if r.Walking {
+				continue
+			}
+			legs = []Leg{r}
+			uid = eventUID(r.token, legs, r.index+1)
+		default:
+			continue
+		}
+		event, ok := icsEvent(legs, uid, berlin, now)
+		if ok {
+			lines = append(lines, event...)
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	return b.String(), nil
+}
+
+func icsEvent(legs []Leg, uid string, berlin *time.Location, now time.Time) ([]string, bool) {
+	var rides []Leg
+	for _, leg := range legs {
+		if !leg.Walking {
+			rides = append(rides, leg)
+		}
+	}
+	if len(legs) == 0 {
+		return nil, false
+	}
+	first, last := legs[0], legs[len(legs)-1]
+	start, errStart := time.Parse(time.RFC3339, pickTime(first.Departure, first.PlannedDep))
+	end, errEnd := time.Parse(time.RFC3339, pickTime(last.Arrival, last.PlannedArr))
+	if errStart != nil || errEnd != nil {
+		return nil, false
+	}
+	route := locationName(first.Origin) + " → " + locationName(last.Destination)
+
+	var names, description []string
+	cancelled := false
+	for _, leg := range rides {
+		names = append(names, legLine(leg))
+		description = append(description, fmt.Sprintf("%s: %s %s%s → %s %s%s",
+			legLine(leg),
+			icsClock(pickTime(leg.Departure, leg.PlannedDep), berlin),
+			locationName(leg.Origin),
+			icsPlatform(pickString(leg.DeparturePlatform, leg.PlannedDeparturePlatform)),
+			icsClock(pickTime(leg.Arrival, leg.PlannedArr), berlin),
+			locationName(leg.Destination),
+			icsPlatform(pickString(leg.ArrivalPlatform, leg.PlannedArrivalPlatform)),
+		))
+		cancelled = cancelled || leg.Cancelled
+	}
+	summary := route
+	if len(names) > 0 {
+		summary = strings.Join(names, ", ") + " " + route
+	}
+
+	event := []string{
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		"DTSTAMP:" + now.UTC().Format(icsLayout) + "Z",
+		"DTSTART;TZID=" + icsTimeZone + ":" + start.In(berlin).Format(icsLayout),
+		"DTEND;TZID=" + icsTimeZone + ":" + end.In(berlin).Format(icsLayout),
+		"SUMMARY:" + escapeICS(summary),
+		"LOCATION:" + escapeICS(route),
+		"DESCRIPTION:" + escapeICS(strings.Join(description, "\n")),
+	}
+	if cancelled {
+		event = append(event, "STATUS:CANCELLED")
+	}
+	return append(event, "END:VEVENT"), true
+}
+
+// eventUID hashes the refresh token, or the leg times and stops when the
+// journey has none. Legs get their 1-based index as a suffix.
+func eventUID(token string, legs []Leg, index int) string {
+	key := token
+	if strings.TrimSpace(key) == "" {
+		for _, leg := range legs {
+			key += leg.PlannedDep + "|" + locationName(leg.Origin) + "|" + leg.PlannedArr + "|" + locationName(leg.Destination) + "|"
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	uid := hex.EncodeToString(sum[:16])
+	if index > 0 {
+		uid += fmt.Sprintf("-%d", index)
+	}
+	return uid + "@dbrest"
+}
+
+func icsClock(value string, berlin *time.Location) string {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return at.In(berlin).Format("15:04")
+}
+
+func icsPlatform(platform string) string {
+	if platform == "-" {
+		return ""
+	}
+	return " (platform " + platform + ")"
+}
+
+// escapeICS escapes a TEXT value (RFC 5545 section 3.3.11).
+func escapeICS(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// foldICSLine splits lines longer than 75 octets, continuing with a space
+// and never inside a UTF-8 sequence.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > icsLineMax {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
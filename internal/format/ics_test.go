@@ -0,0 +1,66 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTableICS(t *testing.T) {
+	data := []byte(`{"journeys":[{"refreshToken":"tok","legs":[` +
+		`{"origin":{"name":"Berlin Hbf"},"destination":{"name":"Hamburg Hbf"},"plannedDeparture":"2024-07-01T06:00:00Z","plannedArrival":"2024-07-01T08:00:00Z","line":{"name":"ICE 1"},"departurePlatform":"7"},` +
+		`{"walking":true,"origin":{"name":"Hamburg Hbf"},"destination":{"name":"Hamburg, Dammtor"},"plannedDeparture":"2024-07-01T08:00:00Z","plannedArrival":"2024-07-01T08:10:00Z"}]}]}`)
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	journeys, err := JourneysTable(data, JourneyOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := journeys.ICS(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"TZID:Europe/Berlin\r\n",
+		"DTSTAMP:20240630T120000Z\r\n",
+		"DTSTART;TZID=Europe/Berlin:20240701T080000\r\n",
+		"DTEND;TZID=Europe/Berlin:20240701T101000\r\n",
+		"SUMMARY:ICE 1 Berlin Hbf → Hamburg\\, Dammtor\r\n",
+		"DESCRIPTION:ICE 1: 08:00 Berlin Hbf (platform 7) → 10:00 Hamburg Hbf\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	legs, err := JourneyLegsTable(data, JourneyOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	perLeg, err := legs.ICS(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Count(perLeg, "BEGIN:VEVENT") != 1 {
+		t.Fatalf("expected one event for the ride, got:\n%s", perLeg)
+	}
+	journeyUID := eventUID("tok", nil, 0)
+	if !strings.Contains(got, "UID:"+journeyUID) || !strings.Contains(perLeg, "UID:"+strings.TrimSuffix(journeyUID, "@dbrest")+"-1@dbrest") {
+		t.Fatalf("unexpected UIDs:\n%s\n%s", got, perLeg)
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("ä", 40)
+	folded := foldICSLine(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > icsLineMax {
+			t.Fatalf("line longer than %d octets: %q", icsLineMax, part)
+		}
+	}
+	if strings.ReplaceAll(folded, "\r\n ", "") != line {
+		t.Fatalf("unfolding changed the line: %q", folded)
+	}
+}
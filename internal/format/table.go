@@ -0,0 +1,294 @@
+package format
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ColumnKind describes how a column is aligned and styled.
+type ColumnKind int
+
+const (
+	KindText ColumnKind = iota
+	KindNumber
+	KindDelay
+	// KindTime cells hold RFC 3339 times, rewritten by FormatTimes.
+	KindTime
+)
+
+// Column is a named table column.
+type Column struct {
+	Name string
+	Kind ColumnKind
+	// Extra columns are left out unless selected with Select.
+	Extra bool
+}
+
+// Row is one table row. Cells line up with Table.Columns.
+type Row struct {
+	Cells     []string
+	Cancelled bool
+	// Record is the typed value the row was built from, for templates.
+	Record any
+}
+
+// Table is the intermediate form shared by the plain and human renderers.
+type Table struct {
+	Columns []Column
+	Rows    []Row
+	// shape is a line drawn under the rows by GeoJSON, e.g. a trip's polyline.
+	shape *shape
+}
+
+// TableOptions controls the human table renderer.
+type TableOptions struct {
+	// Width is the maximum line width; zero means unlimited.
+	Width int
+	// Color enables ANSI styling: red delays, struck-through cancellations.
+	Color bool
+}
+
+const (
+	columnGap      = "  "
+	minColumnWidth = 6
+	ellipsis       = "…"
+
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiStrike = "\x1b[9m"
+	ansiRed    = "\x1b[31m"
+)
+
+func tableOf(fields []Column) Table {
+	return Table{Columns: slices.Clone(fields)}
+}
+
+func (t *Table) add(record any, cancelled bool, cells ...string) {
+	t.Rows = append(t.Rows, Row{Cells: cells, Cancelled: cancelled, Record: record})
+}
+
+// Plain renders tab-separated lines, optionally preceded by a header row.
+func (t Table) Plain(withHeader bool) string {
+	t = t.visible()
+	if len(t.Rows) == 0 {
+		if withHeader {
+			return "no results\n"
+		}
+		return ""
+	}
+	var b strings.Builder
+	if withHeader {
+		names := make([]string, len(t.Columns))
+		for i, col := range t.Columns {
+			names[i] = col.Name
+		}
+		b.WriteString(strings.Join(names, "\t"))
+		b.WriteString("\n")
+	}
+	for _, row := range t.Rows {
+		b.WriteString(strings.Join(row.Cells, "\t"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// CSV renders comma-separated records with RFC 4180 quoting, optionally
+// preceded by a header row. Unlike Plain, cells may contain any character.
+func (t Table) CSV(withHeader bool) string {
+	return t.delimited(',', withHeader)
+}
+
+// TSV is like CSV but separates fields with tabs.
+func (t Table) TSV(withHeader bool) string {
+	return t.delimited('\t', withHeader)
+}
+
+func (t Table) delimited(comma rune, withHeader bool) string {
+	t = t.visible()
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Comma = comma
+	if withHeader {
+		names := make([]string, len(t.Columns))
+		for i, col := range t.Columns {
+			names[i] = col.Name
+		}
+		_ = w.Write(names)
+	}
+	for _, row := range t.Rows {
+		_ = w.Write(row.Cells)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// NDJSON renders one JSON object per row, keyed by column name in column
+// order. Missing values ("-") become null and number columns that hold a
+// number are written as JSON numbers; everything else is a string.
+func (t Table) NDJSON() string {
+	t = t.visible()
+	var b strings.Builder
+	for _, row := range t.Rows {
+		writeJSONRow(&b, t.Columns, row)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// writeJSONRow writes row as a JSON object keyed by column name.
+func writeJSONRow(b *strings.Builder, columns []Column, row Row) {
+	b.WriteString("{")
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.Write(jsonString(col.Name))
+		b.WriteString(":")
+		b.Write(jsonCell(col, row.Cells[i]))
+	}
+	b.WriteString("}")
+}
+
+func jsonCell(col Column, cell string) []byte {
+	if cell == "-" {
+		return []byte("null")
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil && col.Kind == KindNumber && json.Valid([]byte(cell)) {
+		return []byte(cell)
+	}
+	return jsonString(cell)
+}
+
+func jsonString(s string) []byte {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// rightAligned reports whether cells of the kind are right-aligned.
+func (k ColumnKind) rightAligned() bool {
+	return k == KindNumber || k == KindDelay
+}
+
+// Render renders an aligned table with a header row. Text columns are
+// shrunk and truncated with an ellipsis until the table fits opts.Width.
+func (t Table) Render(opts TableOptions) string {
+	t = t.visible()
+	if len(t.Rows) == 0 {
+		return "no results\n"
+	}
+	widths := t.fitWidths(opts.Width)
+	var b strings.Builder
+	for i, col := range t.Columns {
+		text := truncate(col.Name, widths[i])
+		styled := text
+		if opts.Color {
+			styled = ansiBold + text + ansiReset
+		}
+		writeCell(&b, i, len(t.Columns), pad(text, styled, widths[i], col.Kind.rightAligned()))
+	}
+	b.WriteString("\n")
+	for _, row := range t.Rows {
+		for i, col := range t.Columns {
+			value := ""
+			if i < len(row.Cells) {
+				value = row.Cells[i]
+			}
+			text := truncate(value, widths[i])
+			styled := text
+			if opts.Color {
+				styled = styleCell(text, col.Kind, row.Cancelled)
+			}
+			writeCell(&b, i, len(t.Columns), pad(text, styled, widths[i], col.Kind.rightAligned()))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (t Table) fitWidths(limit int) []int {
+	widths := make([]int, len(t.Columns))
+	for i, col := range t.Columns {
+		widths[i] = utf8.RuneCountInString(col.Name)
+	}
+	for _, row := range t.Rows {
+		for i := range t.Columns {
+			if i < len(row.Cells) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(row.Cells[i]))
+			}
+		}
+	}
+	if limit <= 0 {
+		return widths
+	}
+	total := len(columnGap) * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > limit {
+		widest := -1
+		for i, col := range t.Columns {
+			if col.Kind != KindText || widths[i] <= minColumnWidth {
+				continue
+			}
+			if widest < 0 || widths[i] > widths[widest] {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+func writeCell(b *strings.Builder, index, count int, cell string) {
+	if index == count-1 {
+		cell = strings.TrimRight(cell, " ")
+	}
+	b.WriteString(cell)
+	if index < count-1 {
+		b.WriteString(columnGap)
+	}
+}
+
+func styleCell(text string, kind ColumnKind, cancelled bool) string {
+	var codes string
+	if cancelled {
+		codes += ansiStrike
+	}
+	if kind == KindDelay && strings.HasPrefix(text, "+") {
+		codes += ansiRed
+	}
+	if codes == "" {
+		return text
+	}
+	return codes + text + ansiReset
+}
+
+func truncate(value string, width int) string {
+	if utf8.RuneCountInString(value) <= width {
+		return value
+	}
+	if width <= 1 {
+		return string([]rune(value)[:width])
+	}
+	return string([]rune(value)[:width-1]) + ellipsis
+}
+
+// pad aligns styled to width, measuring the visible text without escape codes.
+func pad(text, styled string, width int, right bool) string {
+	gap := width - utf8.RuneCountInString(text)
+	if gap <= 0 {
+		return styled
+	}
+	if right {
+		return strings.Repeat(" ", gap) + styled
+	}
+	return styled + strings.Repeat(" ", gap)
+}
@@ -0,0 +1,74 @@
+package format
+
+import "testing"
+
+func TestTableRenderAlignsAndTruncates(t *testing.T) {
+	data := []byte(`[{"when":"12:00","line":{"name":"S1"},"direction":"Oranienburg über Frohnau","platform":"1","delay":120},` +
+		`{"when":"12:05","line":{"name":"RE1"},"direction":"Magdeburg","platform":"12","delay":0,"cancelled":true}]`)
+	table, err := StopoversTable(data)
+	if err != nil {
+		t.Fatalf("StopoversTable error: %v", err)
+	}
+
+	out := table.Render(TableOptions{Width: 50})
+	expected := "time   line  direction  platform  delay  status\n" +
+		"12:00  S1    Oranienb…  1           +2m  -\n" +
+		"12:05  RE1   Magdeburg  12           0m  cancelled\n"
+	if out != expected {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestTableRenderColor(t *testing.T) {
+	data := []byte(`[{"when":"12:00","line":{"name":"S1"},"direction":"Frohnau","delay":120},` +
+		`{"when":"12:05","line":{"name":"S2"},"direction":"Bernau","cancelled":true}]`)
+	table, err := StopoversTable(data)
+	if err != nil {
+		t.Fatalf("StopoversTable error: %v", err)
+	}
+
+	out := table.Render(TableOptions{Color: true})
+	expected := "\x1b[1mtime\x1b[0m   \x1b[1mline\x1b[0m  \x1b[1mdirection\x1b[0m  \x1b[1mplatform\x1b[0m  \x1b[1mdelay\x1b[0m  \x1b[1mstatus\x1b[0m\n" +
+		"12:00  S1    Frohnau    -           \x1b[31m+2m\x1b[0m  -\n" +
+		"\x1b[9m12:05\x1b[0m  \x1b[9mS2\x1b[0m    \x1b[9mBernau\x1b[0m     \x1b[9m-\x1b[0m             \x1b[9m-\x1b[0m  \x1b[9mcancelled\x1b[0m\n"
+	if out != expected {
+		t.Fatalf("unexpected output:\n%q", out)
+	}
+}
+
+func TestTableCSVQuotes(t *testing.T) {
+	table := tableOf([]Column{{Name: "line"}, {Name: "direction"}})
+	table.add(nil, false, "S1", "Oranienburg, \"Nord\"")
+	table.add(nil, false, "RE1", "Magdeburg\tHbf\nGleis 3")
+
+	csv := table.CSV(true)
+	expected := "line,direction\n" +
+		"S1,\"Oranienburg, \"\"Nord\"\"\"\n" +
+		"RE1,\"Magdeburg\tHbf\nGleis 3\"\n"
+	if csv != expected {
+		t.Fatalf("unexpected csv:\n%q", csv)
+	}
+
+	tsv := table.TSV(false)
+	expected = "S1\t\"Oranienburg, \"\"Nord\"\"\"\n" +
+		"RE1\t\"Magdeburg\tHbf\nGleis 3\"\n"
+	if tsv != expected {
+		t.Fatalf("unexpected tsv:\n%q", tsv)
+	}
+}
+
+func TestTableNDJSON(t *testing.T) {
+	data := []byte(`[{"id":"8011160","name":"Berlin Hbf","type":"stop","latitude":52.525,"longitude":13.369,"distance":120},` +
+		`{"id":"1","name":"Tab\there","type":"poi"}]`)
+	table, err := LocationsTable(data)
+	if err != nil {
+		t.Fatalf("LocationsTable error: %v", err)
+	}
+
+	out := table.NDJSON()
+	expected := `{"id":"8011160","name":"Berlin Hbf","type":"stop","latitude":52.525000,"longitude":13.369000,"distance_m":120}` + "\n" +
+		`{"id":"1","name":"Tab\there","type":"poi","latitude":null,"longitude":null,"distance_m":null}` + "\n"
+	if out != expected {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
@@ -0,0 +1,107 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// ParseTemplate parses a per-record output template. Besides the record's
+// fields and methods, templates can call:
+//
+//	time .When              format an RFC 3339 time as times asks
+//	time "15:04" .When      format it with a Go layout instead
+//	delay .Delay            a delay in seconds as +2m, +30s or 0m
+//	pad 20 .Direction       pad to a width, left-aligned
+//	padLeft 4 .Platform     pad to a width, right-aligned
+//
+// Both forms of time convert to times.Location when it is set.
+func ParseTemplate(text string, times TimeOptions) (*template.Template, error) {
+	return template.New("output").Option("missingkey=zero").Funcs(template.FuncMap{
+		"time":    templateTime(times),
+		"delay":   formatDelay,
+		"pad":     func(width int, value any) string { return padText(fmt.Sprint(value), width, false) },
+		"padLeft": func(width int, value any) string { return padText(fmt.Sprint(value), width, true) },
+	}).Parse(text)
+}
+
+// Template executes tmpl once per row and puts each result on its own
+// line. Rows built from an API record get that record (Location, Stopover,
+// Journey, Leg, TripStop or Movement); other rows get a map of column
+// name to cell.
+func (t Table) Template(tmpl *template.Template) (string, error) {
+	var b strings.Builder
+	for _, row := range t.Rows {
+		var data any = row.Record
+		if data == nil {
+			cells := make(map[string]string, len(t.Columns))
+			for i, col := range t.Columns {
+				cells[col.Name] = row.Cells[i]
+			}
+			data = cells
+		}
+		start := b.Len()
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", err
+		}
+		if b.Len() == start || !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// String returns the line name, so templates can print {{.Line}}.
+func (l Line) String() string {
+	return l.Name
+}
+
+// MinutesUntil returns the whole minutes from now until the stopover's
+// (realtime or planned) time, negative once it has passed.
+func (s Stopover) MinutesUntil() int {
+	return minutesUntil(pickTime(s.When, s.PlannedWhen))
+}
+
+// MinutesUntil returns the whole minutes from now until the leg departs.
+func (l Leg) MinutesUntil() int {
+	return minutesUntil(pickTime(l.Departure, l.PlannedDep))
+}
+
+func minutesUntil(value string) int {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0
+	}
+	return int(time.Until(at).Minutes())
+}
+
+func templateTime(times TimeOptions) func(args ...string) (string, error) {
+	return func(args ...string) (string, error) {
+		if len(args) == 0 || len(args) > 2 {
+			return "", fmt.Errorf("time: expected an optional layout and a time, got %d arguments", len(args))
+		}
+		value := args[len(args)-1]
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return pickString(value, ""), nil
+		}
+		opts := times
+		opts.Now = time.Now()
+		if len(args) == 2 {
+			opts.Style, opts.Layout, opts.Relative = TimeLayout, args[0], false
+		}
+		return formatTime(value, opts), nil
+	}
+}
+
+func padText(text string, width int, right bool) string {
+	gap := width - utf8.RuneCountInString(text)
+	if gap <= 0 {
+		return text
+	}
+	if right {
+		return strings.Repeat(" ", gap) + text
+	}
+	return text + strings.Repeat(" ", gap)
+}
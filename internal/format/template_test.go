@@ -0,0 +1,64 @@
+package format
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTableTemplate(t *testing.T) {
+	when := time.Now().Add(10*time.Minute + 30*time.Second).Format(time.RFC3339)
+	data := []byte(`[{"when":"` + when + `","plannedWhen":"2024-02-01T12:00:00+01:00","line":{"name":"S1"},"direction":"Wannsee","platform":"2","delay":120}]`)
+	table, err := StopoversTable(data)
+	if err != nil {
+		t.Fatalf("StopoversTable error: %v", err)
+	}
+	tmpl, err := ParseTemplate(`{{.Line | pad 4}}→ {{.Direction}} in {{.MinutesUntil}}m ({{delay .Delay}}, planned {{time "15:04" .PlannedWhen}}) [{{padLeft 3 .Platform}}]`, TimeOptions{})
+	if err != nil {
+		t.Fatalf("ParseTemplate error: %v", err)
+	}
+
+	out, err := table.Template(tmpl)
+	if err != nil {
+		t.Fatalf("Template error: %v", err)
+	}
+	if out != "S1  → Wannsee in 10m (+2m, planned 12:00) [  2]\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestTableTemplateWithoutRecords(t *testing.T) {
+	table := tableOf([]Column{{Name: "key"}, {Name: "value"}})
+	table.add(nil, false, "timeout", "10s")
+	tmpl, err := ParseTemplate(`{{.key}}={{.value}}`, TimeOptions{})
+	if err != nil {
+		t.Fatalf("ParseTemplate error: %v", err)
+	}
+
+	out, err := table.Template(tmpl)
+	if err != nil {
+		t.Fatalf("Template error: %v", err)
+	}
+	if out != "timeout=10s\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestTemplateTimeHonoursTimeOptions(t *testing.T) {
+	data := []byte(`[{"when":"2024-02-01T12:00:00+01:00","line":{"name":"S1"}}]`)
+	table, err := StopoversTable(data)
+	if err != nil {
+		t.Fatalf("StopoversTable error: %v", err)
+	}
+	tmpl, err := ParseTemplate(`{{time .When}} {{time "15:04" .When}} {{time .PlannedWhen}}`, TimeOptions{Style: TimeLayout, Layout: "Jan 2 15:04", Location: time.UTC})
+	if err != nil {
+		t.Fatalf("ParseTemplate error: %v", err)
+	}
+
+	out, err := table.Template(tmpl)
+	if err != nil {
+		t.Fatalf("Template error: %v", err)
+	}
+	if out != "Feb 1 11:00 11:00 -\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
@@ -0,0 +1,106 @@
+package format
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeStyle selects how FormatTimes writes time cells.
+type TimeStyle int
+
+const (
+	// TimeISO writes RFC 3339, the API's own format.
+	TimeISO TimeStyle = iota
+	// TimeLayout writes TimeOptions.Layout.
+	TimeLayout
+	// TimeRelative writes "in 5 min", "3 min ago" or "now".
+	TimeRelative
+)
+
+// TimeOptions controls FormatTimes.
+type TimeOptions struct {
+	Style TimeStyle
+	// Layout is the Go layout for TimeLayout, e.g. "15:04".
+	Layout string
+	// Relative appends " (in 5 min)" to TimeLayout cells.
+	Relative bool
+	// Location converts every time; nil keeps each time's own offset.
+	Location *time.Location
+	// Now is the reference for relative times.
+	Now time.Time
+}
+
+// FormatTimes rewrites the time columns of t. Cells that are not RFC 3339
+// times, such as "-", are left as they are, and so is everything when
+// opts asks for ISO without a Location.
+func (t Table) FormatTimes(opts TimeOptions) Table {
+	if opts.Style == TimeISO && opts.Location == nil {
+		return t
+	}
+	var columns []int
+	for i, col := range t.Columns {
+		if col.Kind == KindTime {
+			columns = append(columns, i)
+		}
+	}
+	if len(columns) == 0 {
+		return t
+	}
+	out := Table{Columns: t.Columns, Rows: make([]Row, len(t.Rows)), shape: t.shape}
+	for r, row := range t.Rows {
+		cells := append([]string(nil), row.Cells...)
+		for _, i := range columns {
+			if i < len(cells) {
+				cells[i] = formatTime(cells[i], opts)
+			}
+		}
+		out.Rows[r] = Row{Cells: cells, Cancelled: row.Cancelled, Record: row.Record}
+	}
+	return out
+}
+
+func formatTime(value string, opts TimeOptions) string {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	if opts.Location != nil {
+		at = at.In(opts.Location)
+	}
+	switch opts.Style {
+	case TimeRelative:
+		return relativeTime(at, opts.Now)
+	case TimeLayout:
+		text := at.Format(opts.Layout)
+		if opts.Relative {
+			text += " (" + relativeTime(at, opts.Now) + ")"
+		}
+		return text
+	default:
+		return at.Format(time.RFC3339)
+	}
+}
+
+// relativeTime describes at from now in whole minutes, hours and days.
+func relativeTime(at, now time.Time) string {
+	d := at.Sub(now).Round(time.Minute)
+	if d == 0 {
+		return "now"
+	}
+	span := d.Abs()
+	var text string
+	switch {
+	case span < time.Hour:
+		text = fmt.Sprintf("%d min", int(span.Minutes()))
+	case span < 24*time.Hour && span%time.Hour == 0:
+		text = fmt.Sprintf("%d h", int(span.Hours()))
+	case span < 24*time.Hour:
+		text = fmt.Sprintf("%d h %d min", int(span.Hours()), int(span.Minutes())%60)
+	default:
+		text = fmt.Sprintf("%d d", int(span.Hours())/24)
+	}
+	if d < 0 {
+		return text + " ago"
+	}
+	return "in " + text
+}
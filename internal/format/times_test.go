@@ -0,0 +1,51 @@
+package format
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTableFormatTimes(t *testing.T) {
+	data := []byte(`[{"when":"2024-02-01T08:05:00+01:00","plannedWhen":"2024-02-01T08:00:00+01:00","line":{"name":"S1"}},{"line":{"name":"S2"}}]`)
+	table, err := StopoversTable(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table, err = table.Select([]string{"time", "planned_when", "line"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2024, 2, 1, 7, 0, 0, 0, time.UTC)
+
+	human := table.FormatTimes(TimeOptions{Style: TimeLayout, Layout: "15:04", Relative: true, Now: now})
+	if got := human.Plain(false); got != "08:05 (in 5 min)\t08:00 (now)\tS1\n-\t-\tS2\n" {
+		t.Fatalf("unexpected human times: %q", got)
+	}
+
+	utc := table.FormatTimes(TimeOptions{Style: TimeISO, Location: time.UTC})
+	if got := utc.Rows[0].Cells[0]; got != "2024-02-01T07:05:00Z" {
+		t.Fatalf("unexpected iso time: %q", got)
+	}
+
+	if got := table.FormatTimes(TimeOptions{}).Rows[0].Cells[0]; got != "2024-02-01T08:05:00+01:00" {
+		t.Fatalf("expected the API time unchanged, got %q", got)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[time.Duration]string{
+		20 * time.Second:                "now",
+		5 * time.Minute:                 "in 5 min",
+		-3 * time.Minute:                "3 min ago",
+		2 * time.Hour:                   "in 2 h",
+		90 * time.Minute:                "in 1 h 30 min",
+		-50 * time.Hour:                 "2 d ago",
+		59*time.Minute + 40*time.Second: "in 1 h",
+	}
+	for offset, want := range cases {
+		if got := relativeTime(now.Add(offset), now); got != want {
+			t.Errorf("relativeTime(%s) = %q, want %q", offset, got, want)
+		}
+	}
+}